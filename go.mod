@@ -3,10 +3,14 @@ module api
 go 1.19
 
 require (
-	github.com/badoux/checkmail v1.2.1 // indirect
-	github.com/dgrijalva/jwt-go v3.2.0+incompatible // indirect
-	github.com/go-sql-driver/mysql v1.7.0 // indirect
-	github.com/gorilla/mux v1.8.0 // indirect
-	github.com/joho/godotenv v1.4.0 // indirect
-	golang.org/x/crypto v0.4.0 // indirect
+	github.com/badoux/checkmail v1.2.1
+	github.com/go-sql-driver/mysql v1.7.0
+	github.com/golang-jwt/jwt/v5 v5.2.1
+	github.com/gorilla/mux v1.8.0
+	github.com/gorilla/websocket v1.5.0
+	github.com/joho/godotenv v1.4.0
+	golang.org/x/crypto v0.4.0
+	golang.org/x/image v0.5.0
 )
+
+require golang.org/x/sys v0.3.0 // indirect
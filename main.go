@@ -1,8 +1,12 @@
 package main
 
 import (
+	"api/src/autenticacao"
 	"api/src/config"
+	"api/src/email"
+	"api/src/midia"
 	"api/src/router"
+	"api/src/seguranca"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,6 +14,17 @@ import (
 
 func main() {
 	config.Carregar()
+	if erro := autenticacao.CarregarChaves(); erro != nil {
+		log.Fatal(erro)
+	}
+	if erro := seguranca.CarregarSenhasVazadas(); erro != nil {
+		log.Fatal(erro)
+	}
+	email.Carregar()
+	if erro := midia.Carregar(); erro != nil {
+		log.Fatal(erro)
+	}
+
 	r := router.Gerar()
 
 	fmt.Printf("Escutando na Porta %d", config.Porta)
@@ -1,6 +1,24 @@
 CREATE DATABASE IF NOT EXISTS go_lesson;
 USE go_lesson;
 
+DROP TABLE IF EXISTS mensagens;
+DROP TABLE IF EXISTS conversa_membros;
+DROP TABLE IF EXISTS conversas;
+DROP TABLE IF EXISTS preferencias_notificacao;
+DROP TABLE IF EXISTS notificacao_atores;
+DROP TABLE IF EXISTS notificacoes;
+DROP TABLE IF EXISTS mencoes;
+DROP TABLE IF EXISTS tags_seguidas;
+DROP TABLE IF EXISTS publicacao_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS repostagens;
+DROP TABLE IF EXISTS palavras_silenciadas;
+DROP TABLE IF EXISTS usuarios_silenciados;
+DROP TABLE IF EXISTS bloqueios;
+DROP TABLE IF EXISTS publicacao_midias;
+DROP TABLE IF EXISTS midias;
+DROP TABLE IF EXISTS links_magicos;
+DROP TABLE IF EXISTS sessoes;
 DROP TABLE IF EXISTS usuarios;
 DROP TABLE IF EXISTS seguidores;
 DROP TABLE IF EXISTS publicacao;
@@ -11,9 +29,17 @@ CREATE TABLE usuarios(
     nome varchar(50) not null,
     nick varchar(50) not null unique,
     email varchar(50) not null unique,
-    senha varchar(100) not null,
+    senha varchar(255) not null,
+    bio varchar(300) null,
+    avatar_url varchar(255) null,
+    capa_url varchar(255) null,
+    localizacao varchar(100) null,
+    website varchar(255) null,
+    data_nascimento date null,
+    privada boolean not null default false,
+    versao int unsigned not null default 1,
     criadoEm timestamp default current_timestamp()
-) ENGINE = INNODB;
+) ENGINE = INNODB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
 
 
 CREATE TABLE seguidores(
@@ -25,6 +51,8 @@ CREATE TABLE seguidores(
     FOREIGN KEY (seguidor_id)
     REFERENCES usuarios(id)
     ON DELETE CASCADE,
+    pendente boolean not null default false,
+    criadoEm timestamp default current_timestamp(),
 
     primary key (usuario_id, seguidor_id)
 ) ENGINE = INNODB;
@@ -39,5 +67,286 @@ CREATE TABLE publicacao(
     REFERENCES usuarios(id)
     ON DELETE CASCADE,
     curtidas int default 0,
+    visibilidade enum('publica', 'seguidores', 'somente_eu') not null default 'publica',
+    citacao_id int null,
+    FOREIGN KEY (citacao_id)
+    REFERENCES publicacao(id)
+    ON DELETE SET NULL,
+    versao int unsigned not null default 1,
     criadaEm timestamp default current_timestamp()
-) ENGINE = INNODB;
\ No newline at end of file
+) ENGINE = INNODB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+
+CREATE TABLE sessoes(
+    id varchar(32) primary key,
+    usuario_id int not null,
+    FOREIGN KEY (usuario_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    user_agent varchar(255) not null,
+    ip varchar(45) not null,
+    criadaEm timestamp default current_timestamp(),
+    ultimoAcessoEm timestamp default current_timestamp(),
+    expiraEm timestamp not null,
+    revogadaEm timestamp null,
+
+    index (usuario_id, user_agent)
+) ENGINE = INNODB;
+
+
+CREATE TABLE links_magicos(
+    token_hash char(64) primary key,
+    usuario_id int not null,
+    FOREIGN KEY (usuario_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    expiraEm timestamp not null,
+    usadoEm timestamp null,
+    criadoEm timestamp default current_timestamp()
+) ENGINE = INNODB;
+
+
+CREATE TABLE midias(
+    id int auto_increment primary key,
+    usuario_id int not null,
+    FOREIGN KEY (usuario_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    hash char(64) not null,
+    tipo varchar(30) not null,
+    extensao varchar(5) not null,
+    largura int not null,
+    altura int not null,
+    tamanho int not null,
+    criadaEm timestamp default current_timestamp(),
+
+    index (hash)
+) ENGINE = INNODB;
+
+
+CREATE TABLE publicacao_midias(
+    publicacao_id int not null,
+    FOREIGN KEY (publicacao_id)
+    REFERENCES publicacao(id)
+    ON DELETE CASCADE,
+    midia_id int not null,
+    FOREIGN KEY (midia_id)
+    REFERENCES midias(id)
+    ON DELETE CASCADE,
+    posicao int not null,
+
+    primary key (publicacao_id, midia_id)
+) ENGINE = INNODB;
+
+
+CREATE TABLE bloqueios(
+    usuario_id int not null,
+    FOREIGN KEY (usuario_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    bloqueado_id int not null,
+    FOREIGN KEY (bloqueado_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    criadoEm timestamp default current_timestamp(),
+
+    primary key (usuario_id, bloqueado_id),
+    index (bloqueado_id)
+) ENGINE = INNODB;
+
+
+CREATE TABLE usuarios_silenciados(
+    usuario_id int not null,
+    FOREIGN KEY (usuario_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    silenciado_id int not null,
+    FOREIGN KEY (silenciado_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    expiraEm timestamp null,
+    criadoEm timestamp default current_timestamp(),
+
+    primary key (usuario_id, silenciado_id)
+) ENGINE = INNODB;
+
+
+CREATE TABLE palavras_silenciadas(
+    id int auto_increment primary key,
+    usuario_id int not null,
+    FOREIGN KEY (usuario_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    termo varchar(100) not null,
+    expiraEm timestamp null,
+    criadoEm timestamp default current_timestamp(),
+
+    unique (usuario_id, termo)
+) ENGINE = INNODB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+
+CREATE TABLE repostagens(
+    usuario_id int not null,
+    FOREIGN KEY (usuario_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    publicacao_id int not null,
+    FOREIGN KEY (publicacao_id)
+    REFERENCES publicacao(id)
+    ON DELETE CASCADE,
+    criadaEm timestamp default current_timestamp(),
+
+    primary key (usuario_id, publicacao_id),
+    index (publicacao_id)
+) ENGINE = INNODB;
+
+
+CREATE TABLE tags(
+    id int auto_increment primary key,
+    nome varchar(50) not null unique
+) ENGINE = INNODB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+
+CREATE TABLE publicacao_tags(
+    publicacao_id int not null,
+    FOREIGN KEY (publicacao_id)
+    REFERENCES publicacao(id)
+    ON DELETE CASCADE,
+    tag_id int not null,
+    FOREIGN KEY (tag_id)
+    REFERENCES tags(id)
+    ON DELETE CASCADE,
+    posicao int not null,
+
+    primary key (publicacao_id, tag_id),
+    index (tag_id)
+) ENGINE = INNODB;
+
+
+CREATE TABLE tags_seguidas(
+    usuario_id int not null,
+    FOREIGN KEY (usuario_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    tag_id int not null,
+    FOREIGN KEY (tag_id)
+    REFERENCES tags(id)
+    ON DELETE CASCADE,
+    criadoEm timestamp default current_timestamp(),
+
+    primary key (usuario_id, tag_id)
+) ENGINE = INNODB;
+
+
+CREATE TABLE mencoes(
+    publicacao_id int not null,
+    FOREIGN KEY (publicacao_id)
+    REFERENCES publicacao(id)
+    ON DELETE CASCADE,
+    usuario_id int not null,
+    FOREIGN KEY (usuario_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    campo enum('titulo', 'conteudo') not null,
+    inicio int not null,
+    fim int not null,
+
+    primary key (publicacao_id, campo, inicio),
+    index (usuario_id)
+) ENGINE = INNODB;
+
+
+CREATE TABLE notificacoes(
+    id int auto_increment primary key,
+    usuario_id int not null,
+    FOREIGN KEY (usuario_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    tipo varchar(20) not null,
+    publicacao_id int,
+    FOREIGN KEY (publicacao_id)
+    REFERENCES publicacao(id)
+    ON DELETE CASCADE,
+    ator_id int not null,
+    FOREIGN KEY (ator_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    atores int not null default 1,
+    lida bool not null default false,
+    criadaEm timestamp default current_timestamp(),
+    atualizadaEm timestamp default current_timestamp(),
+
+    index (usuario_id, lida)
+) ENGINE = INNODB;
+
+
+CREATE TABLE notificacao_atores(
+    notificacao_id int not null,
+    FOREIGN KEY (notificacao_id)
+    REFERENCES notificacoes(id)
+    ON DELETE CASCADE,
+    ator_id int not null,
+    FOREIGN KEY (ator_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+
+    primary key (notificacao_id, ator_id)
+) ENGINE = INNODB;
+
+
+CREATE TABLE preferencias_notificacao(
+    usuario_id int not null,
+    FOREIGN KEY (usuario_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    tipo varchar(20) not null,
+    ativa bool not null default true,
+
+    primary key (usuario_id, tipo)
+) ENGINE = INNODB;
+
+
+CREATE TABLE conversas(
+    id int auto_increment primary key,
+    tipo enum('direta', 'grupo') not null default 'direta',
+    nome varchar(100),
+    chave varchar(50) unique,
+    criadaEm timestamp default current_timestamp()
+) ENGINE = INNODB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+
+CREATE TABLE conversa_membros(
+    conversa_id int not null,
+    FOREIGN KEY (conversa_id)
+    REFERENCES conversas(id)
+    ON DELETE CASCADE,
+    usuario_id int not null,
+    FOREIGN KEY (usuario_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    admin bool not null default false,
+    desdeID int not null default 0,
+    ultimaLidaID int not null default 0,
+    entrouEm timestamp default current_timestamp(),
+
+    primary key (conversa_id, usuario_id),
+    index (usuario_id)
+) ENGINE = INNODB;
+
+
+CREATE TABLE mensagens(
+    id int auto_increment primary key,
+    conversa_id int not null,
+    FOREIGN KEY (conversa_id)
+    REFERENCES conversas(id)
+    ON DELETE CASCADE,
+    tipo enum('usuario', 'sistema') not null default 'usuario',
+    autor_id int not null,
+    FOREIGN KEY (autor_id)
+    REFERENCES usuarios(id)
+    ON DELETE CASCADE,
+    conteudo varchar(2000) not null,
+    criadaEm timestamp default current_timestamp(),
+
+    index (conversa_id, id)
+) ENGINE = INNODB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
@@ -0,0 +1,165 @@
+package autenticacao
+
+import (
+	"api/src/config"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// chave representa uma chave de assinatura identificada por um kid
+type chave struct {
+	id      string
+	metodo  jwt.SigningMethod
+	privada crypto.Signer
+}
+
+// JWK representa uma chave pública no formato JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS representa o conjunto de chaves públicas publicado pela API
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var (
+	chaves     = map[string]*chave{}
+	chaveAtiva *chave
+)
+
+// CarregarChaves lê as chaves privadas do diretório configurado. Todas as chaves
+// encontradas validam tokens, mas apenas a chave ativa assina tokens novos, o que
+// permite fazer a rotação sem invalidar as sessões existentes
+func CarregarChaves() error {
+	chaves = map[string]*chave{}
+	chaveAtiva = nil
+
+	if config.ChavesDir == "" {
+		log.Println("JWT_CHAVES_DIR não configurado, gerando uma chave temporária")
+		_, privada, erro := ed25519.GenerateKey(rand.Reader)
+		if erro != nil {
+			return erro
+		}
+		chaveAtiva = &chave{id: "temporaria", metodo: jwt.SigningMethodEdDSA, privada: privada}
+		chaves[chaveAtiva.id] = chaveAtiva
+		return nil
+	}
+
+	arquivos, erro := filepath.Glob(filepath.Join(config.ChavesDir, "*.pem"))
+	if erro != nil {
+		return erro
+	}
+	sort.Strings(arquivos)
+
+	for _, arquivo := range arquivos {
+		c, erro := lerChave(arquivo)
+		if erro != nil {
+			return fmt.Errorf("%s: %w", arquivo, erro)
+		}
+		chaves[c.id] = c
+	}
+
+	if len(chaves) == 0 {
+		return fmt.Errorf("Nenhuma chave encontrada em %s", config.ChavesDir)
+	}
+
+	if config.ChaveAtiva == "" {
+		// Sem configuração explícita a última chave em ordem alfabética assina,
+		// então nomes como 2024-01.pem, 2024-06.pem já definem a rotação
+		kid := strings.TrimSuffix(filepath.Base(arquivos[len(arquivos)-1]), ".pem")
+		chaveAtiva = chaves[kid]
+		return nil
+	}
+
+	c, ok := chaves[config.ChaveAtiva]
+	if !ok {
+		return fmt.Errorf("Chave ativa %s não encontrada em %s", config.ChaveAtiva, config.ChavesDir)
+	}
+	chaveAtiva = c
+	return nil
+}
+
+func lerChave(arquivo string) (*chave, error) {
+	conteudo, erro := os.ReadFile(arquivo)
+	if erro != nil {
+		return nil, erro
+	}
+
+	bloco, _ := pem.Decode(conteudo)
+	if bloco == nil {
+		return nil, errors.New("Arquivo não está no formato PEM")
+	}
+
+	var privada interface{}
+	switch bloco.Type {
+	case "RSA PRIVATE KEY":
+		privada, erro = x509.ParsePKCS1PrivateKey(bloco.Bytes)
+	case "PRIVATE KEY":
+		privada, erro = x509.ParsePKCS8PrivateKey(bloco.Bytes)
+	default:
+		return nil, fmt.Errorf("Tipo de bloco PEM não suportado: %s", bloco.Type)
+	}
+	if erro != nil {
+		return nil, erro
+	}
+
+	c := &chave{id: strings.TrimSuffix(filepath.Base(arquivo), ".pem")}
+	switch p := privada.(type) {
+	case *rsa.PrivateKey:
+		c.metodo = jwt.SigningMethodRS256
+		c.privada = p
+	case ed25519.PrivateKey:
+		c.metodo = jwt.SigningMethodEdDSA
+		c.privada = p
+	default:
+		return nil, errors.New("Somente chaves RSA e Ed25519 são suportadas")
+	}
+	return c, nil
+}
+
+// ChavesPublicas retorna as chaves públicas de todas as chaves carregadas
+func ChavesPublicas() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+
+	for _, c := range chaves {
+		jwk := JWK{Kid: c.id, Use: "sig", Alg: c.metodo.Alg()}
+
+		switch publica := c.privada.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publica.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publica.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publica)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
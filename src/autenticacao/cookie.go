@@ -0,0 +1,89 @@
+package autenticacao
+
+import (
+	"api/src/config"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+)
+
+const (
+	// CookieToken guarda o token de autenticação, inacessível ao javascript
+	CookieToken = "devbook_token"
+
+	// CookieCSRF guarda o token CSRF que o front-end deve repetir no cabeçalho
+	CookieCSRF = "devbook_csrf"
+
+	// CabecalhoCSRF é o cabeçalho onde o front-end envia o token CSRF
+	CabecalhoCSRF = "X-CSRF-Token"
+)
+
+// AceitaBearer informa se o token pode vir no cabeçalho Authorization
+func AceitaBearer() bool {
+	return config.ModoAutenticacao != "cookie"
+}
+
+// AceitaCookie informa se o token pode vir no cookie de autenticação
+func AceitaCookie() bool {
+	return config.ModoAutenticacao != "bearer"
+}
+
+// DefinirCookies grava o token e um novo token CSRF nos cookies da resposta
+func DefinirCookies(w http.ResponseWriter, token string) error {
+	csrf := make([]byte, 32)
+	if _, erro := rand.Read(csrf); erro != nil {
+		return erro
+	}
+
+	http.SetCookie(w, novoCookie(CookieToken, token, true))
+	http.SetCookie(w, novoCookie(CookieCSRF, base64.RawURLEncoding.EncodeToString(csrf), false))
+	return nil
+}
+
+// RemoverCookies apaga os cookies de autenticação do navegador
+func RemoverCookies(w http.ResponseWriter) {
+	for _, nome := range []string{CookieToken, CookieCSRF} {
+		cookie := novoCookie(nome, "", nome == CookieToken)
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+	}
+}
+
+// ValidarCSRF confere o token CSRF do cabeçalho com o do cookie (double submit)
+func ValidarCSRF(r *http.Request) error {
+	cookie, erro := r.Cookie(CookieCSRF)
+	if erro != nil || cookie.Value == "" {
+		return errors.New("Token CSRF ausente")
+	}
+
+	cabecalho := r.Header.Get(CabecalhoCSRF)
+	if subtle.ConstantTimeCompare([]byte(cabecalho), []byte(cookie.Value)) != 1 {
+		return errors.New("Token CSRF inválido")
+	}
+	return nil
+}
+
+func novoCookie(nome, valor string, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     nome,
+		Value:    valor,
+		Path:     "/",
+		MaxAge:   int(DuracaoToken.Seconds()),
+		HttpOnly: httpOnly,
+		Secure:   config.CookieSeguro,
+		SameSite: sameSite(),
+	}
+}
+
+func sameSite() http.SameSite {
+	switch config.CookieSameSite {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
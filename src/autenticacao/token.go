@@ -2,6 +2,8 @@ package autenticacao
 
 import (
 	"api/src/config"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
@@ -9,67 +11,118 @@ import (
 	"strings"
 	"time"
 
-	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
 )
 
-// CriarToken token com as permissões de usuário
-func CriarToken(usuarioID uint64) (string, error) {
-	permissoes := jwt.MapClaims{}
-	permissoes["authorized"] = true
-	permissoes["exp"] = time.Now().Add(time.Hour * 6).Unix()
-	permissoes["usuarioId"] = usuarioID
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, permissoes)
-	return token.SignedString([]byte(config.SecretKey)) //secret
+// Permissoes representa as claims dos tokens emitidos pela API
+type Permissoes struct {
+	jwt.RegisteredClaims
+	Nick    string   `json:"nick"`
+	Papeis  []string `json:"roles"`
+	Escopos []string `json:"scopes"`
 }
 
-// ValidarToken verifica se o token na requisição é valido
-func ValidarToken(r *http.Request) error {
-	tokenString := extrairToken(r)
-	token, erro := jwt.Parse(tokenString, retornarChaveDeVerificacao)
-
+// Usuario converte as claims do token no usuário autenticado
+func (p Permissoes) Usuario() (UsuarioAutenticado, error) {
+	usuarioID, erro := strconv.ParseUint(p.Subject, 10, 64)
 	if erro != nil {
-		return erro
-	}
-
-	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return nil
+		return UsuarioAutenticado{}, errors.New("Token inválido")
 	}
 
-	return errors.New("Token Inválido")
+	return UsuarioAutenticado{
+		ID:      usuarioID,
+		Nick:    p.Nick,
+		Papeis:  p.Papeis,
+		Escopos: p.Escopos,
+		TokenID: p.ID,
+	}, nil
 }
 
-func extrairToken(r *http.Request) string {
-	token := r.Header.Get("Authorization")
+// DuracaoToken é o tempo de validade dos tokens emitidos
+const DuracaoToken = time.Hour * 6
 
-	if len(strings.Split(token, " ")) == 2 {
-		return strings.Split(token, " ")[1]
+// NovoTokenID gera um identificador aleatório para o claim jti, que também identifica a sessão
+func NovoTokenID() (string, error) {
+	tokenID := make([]byte, 16)
+	if _, erro := rand.Read(tokenID); erro != nil {
+		return "", erro
 	}
-	return ""
+	return hex.EncodeToString(tokenID), nil
 }
 
-func retornarChaveDeVerificacao(token *jwt.Token) (interface{}, error) {
-	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-		return nil, fmt.Errorf("Método de assinatura inesperado! %v", token.Header["alg"])
+// CriarToken token com as permissões de usuário para a sessão informada
+func CriarToken(usuarioID uint64, nick string, sessaoID string) (string, error) {
+	if chaveAtiva == nil {
+		return "", errors.New("Nenhuma chave de assinatura carregada")
 	}
-	return config.SecretKey, nil
+
+	agora := time.Now()
+	permissoes := Permissoes{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessaoID,
+			Subject:   strconv.FormatUint(usuarioID, 10),
+			Issuer:    config.EmissorToken,
+			Audience:  jwt.ClaimStrings{config.AudienciaToken},
+			IssuedAt:  jwt.NewNumericDate(agora),
+			NotBefore: jwt.NewNumericDate(agora),
+			ExpiresAt: jwt.NewNumericDate(agora.Add(DuracaoToken)),
+		},
+		Nick:    nick,
+		Papeis:  []string{PapelUsuario},
+		Escopos: []string{EscopoLeitura, EscopoEscrita},
+	}
+	token := jwt.NewWithClaims(chaveAtiva.metodo, permissoes)
+	token.Header["kid"] = chaveAtiva.id
+	return token.SignedString(chaveAtiva.privada)
 }
 
-// ExtrairUsuarioID
-func ExtrairUsuarioID(r *http.Request) (uint64, error) {
-	tokenString := extrairToken(r)
-	token, erro := jwt.Parse(tokenString, retornarChaveDeVerificacao)
+// LerToken valida o token da requisição e retorna as suas permissões, informando
+// se o token veio do cookie de autenticação
+func LerToken(r *http.Request) (*Permissoes, bool, error) {
+	tokenString, viaCookie := extrairToken(r)
+
+	permissoes := &Permissoes{}
+	_, erro := jwt.ParseWithClaims(tokenString, permissoes, retornarChaveDeVerificacao,
+		jwt.WithIssuer(config.EmissorToken),
+		jwt.WithAudience(config.AudienciaToken),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(30*time.Second),
+	)
 
 	if erro != nil {
-		return 0, erro
+		return nil, false, erro
+	}
+
+	return permissoes, viaCookie, nil
+}
+
+func extrairToken(r *http.Request) (string, bool) {
+	if AceitaBearer() {
+		token := r.Header.Get("Authorization")
+
+		if len(strings.Split(token, " ")) == 2 {
+			return strings.Split(token, " ")[1], false
+		}
 	}
 
-	if permissoes, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		usuarioID, erro := strconv.ParseUint(fmt.Sprintf("%.0f", permissoes["usuarioId"]), 10, 64)
-		if erro != nil {
-			return 0, erro
+	if AceitaCookie() {
+		if cookie, erro := r.Cookie(CookieToken); erro == nil {
+			return cookie.Value, true
 		}
+	}
+	return "", false
+}
 
-		return usuarioID, nil
+func retornarChaveDeVerificacao(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	c, ok := chaves[kid]
+	if !ok {
+		return nil, fmt.Errorf("Chave de assinatura desconhecida! %v", token.Header["kid"])
+	}
+
+	if token.Method.Alg() != c.metodo.Alg() {
+		return nil, fmt.Errorf("Método de assinatura inesperado! %v", token.Header["alg"])
 	}
-	return 0, errors.New("Token inválido")
+	return c.privada.Public(), nil
 }
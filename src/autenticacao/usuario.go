@@ -0,0 +1,63 @@
+package autenticacao
+
+import (
+	"context"
+	"net/http"
+)
+
+const (
+	// PapelUsuario é o papel de todo usuário cadastrado na rede social
+	PapelUsuario = "usuario"
+
+	// EscopoLeitura permite consultar usuários e publicações
+	EscopoLeitura = "leitura"
+
+	// EscopoEscrita permite criar e alterar dados em nome do usuário
+	EscopoEscrita = "escrita"
+)
+
+// UsuarioAutenticado representa o usuário dono do token da requisição
+type UsuarioAutenticado struct {
+	ID      uint64
+	Nick    string
+	Papeis  []string
+	Escopos []string
+	TokenID string
+}
+
+type chaveContexto struct{}
+
+// TemPapel verifica se o usuário autenticado possui o papel informado
+func (u UsuarioAutenticado) TemPapel(papel string) bool {
+	return contem(u.Papeis, papel)
+}
+
+// TemEscopo verifica se o token do usuário autenticado concede o escopo informado
+func (u UsuarioAutenticado) TemEscopo(escopo string) bool {
+	return contem(u.Escopos, escopo)
+}
+
+// ComUsuario guarda o usuário autenticado no contexto da requisição
+func ComUsuario(r *http.Request, usuario UsuarioAutenticado) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), chaveContexto{}, usuario))
+}
+
+// UsuarioDaRequisicao retorna o usuário guardado pelo middleware de autenticação.
+// Entra em pânico se a rota não passou pelo middleware, já que isso é erro de
+// configuração das rotas e não da requisição
+func UsuarioDaRequisicao(r *http.Request) UsuarioAutenticado {
+	usuario, ok := r.Context().Value(chaveContexto{}).(UsuarioAutenticado)
+	if !ok {
+		panic("autenticacao: " + r.Method + " " + r.URL.Path + " executado sem o middleware de autenticação")
+	}
+	return usuario
+}
+
+func contem(valores []string, valor string) bool {
+	for _, v := range valores {
+		if v == valor {
+			return true
+		}
+	}
+	return false
+}
@@ -3,8 +3,10 @@ package config
 import (
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -16,8 +18,89 @@ var (
 	//Porta onde a API vai estar rodando
 	Porta = 0
 
-	//SecretKey é a chave que vai ser usada para assinar o token
-	SecretKey []byte
+	//ChavesDir é o diretório com as chaves privadas (PEM) usadas para assinar os tokens
+	ChavesDir = ""
+
+	//ChaveAtiva é o kid da chave usada para assinar novos tokens
+	ChaveAtiva = ""
+
+	//EmissorToken é o valor do claim iss dos tokens emitidos
+	EmissorToken = ""
+
+	//AudienciaToken é o valor do claim aud dos tokens emitidos
+	AudienciaToken = ""
+
+	//ModoAutenticacao define onde o token é aceito: bearer, cookie ou ambos
+	ModoAutenticacao = "bearer"
+
+	//CookieSeguro define se o cookie de autenticação só trafega por HTTPS
+	CookieSeguro = true
+
+	//CookieSameSite é a política SameSite dos cookies de autenticação: strict, lax ou none
+	CookieSameSite = "lax"
+
+	//SenhaTamanhoMinimo é a quantidade mínima de caracteres de uma senha
+	SenhaTamanhoMinimo = 8
+
+	//SenhaClassesMinimas é quantas classes (minúsculas, maiúsculas, números, símbolos) a senha deve ter
+	SenhaClassesMinimas = 3
+
+	//SenhasVazadasArquivo é o arquivo com os hashes SHA-1 das senhas vazadas conhecidas
+	SenhasVazadasArquivo = ""
+
+	//HashAlgoritmo é o algoritmo usado nos hashes de senha novos: argon2id ou bcrypt
+	HashAlgoritmo = "argon2id"
+
+	//HashPepper é um segredo do servidor misturado às senhas antes do hash (opcional)
+	HashPepper = ""
+
+	//BcryptCusto é o custo usado nos hashes bcrypt
+	BcryptCusto = 10
+
+	//Argon2Memoria é a memória em KiB usada pelo Argon2id
+	Argon2Memoria = 64 * 1024
+
+	//Argon2Iteracoes é a quantidade de passadas do Argon2id
+	Argon2Iteracoes = 3
+
+	//Argon2Paralelismo é a quantidade de threads do Argon2id
+	Argon2Paralelismo = 2
+
+	//URLApp é o endereço do front-end, usado nos links enviados por email
+	URLApp = ""
+
+	//EmailEnviador define como os emails são entregues: log ou arquivo
+	EmailEnviador = "log"
+
+	//EmailDiretorio é onde o enviador arquivo grava os emails
+	EmailDiretorio = ""
+
+	//URLAPI é o endereço público da API, usado nas URLs das mídias
+	URLAPI = ""
+
+	//MidiaArmazenamento define onde as mídias são guardadas: local ou s3
+	MidiaArmazenamento = "local"
+
+	//MidiaDiretorio é onde o armazenamento local guarda as mídias
+	MidiaDiretorio = ""
+
+	//MidiaTamanhoMaximo é o tamanho máximo em bytes de um arquivo enviado
+	MidiaTamanhoMaximo int64 = 10 << 20
+
+	//S3Endpoint é o endereço do serviço compatível com S3 (ex.: http://localhost:9000 no MinIO)
+	S3Endpoint = ""
+
+	//S3Regiao é a região usada na assinatura das requisições ao S3
+	S3Regiao = ""
+
+	//S3Bucket é o bucket onde as mídias são guardadas
+	S3Bucket = ""
+
+	//S3ChaveAcesso é o access key do S3
+	S3ChaveAcesso = ""
+
+	//S3ChaveSecreta é o secret key do S3
+	S3ChaveSecreta = ""
 )
 
 // Carregar vai inicializar as variáveis de ambiente
@@ -37,8 +120,141 @@ func Carregar() {
 	db_usuario := os.Getenv("DB_USUARIO")
 	db_senha := os.Getenv("DB_SENHA")
 	db_nome := os.Getenv("DB_NOME")
-	db_config := "charset=utf8&parseTime=True&loc=Local"
+	db_config := "charset=utf8mb4&parseTime=True&loc=Local"
 	StringConexaoBanco = fmt.Sprintf("%s:%s@/%s?%s", db_usuario, db_senha, db_nome, db_config)
 
-	SecretKey = []byte(os.Getenv("SECRET_KEY"))
+	ChavesDir = os.Getenv("JWT_CHAVES_DIR")
+	ChaveAtiva = os.Getenv("JWT_CHAVE_ATIVA")
+
+	EmissorToken = os.Getenv("JWT_EMISSOR")
+	if EmissorToken == "" {
+		EmissorToken = "devbook-api"
+	}
+
+	AudienciaToken = os.Getenv("JWT_AUDIENCIA")
+	if AudienciaToken == "" {
+		AudienciaToken = "devbook"
+	}
+
+	switch modo := os.Getenv("AUTH_MODO"); modo {
+	case "bearer", "cookie", "ambos":
+		ModoAutenticacao = modo
+	case "":
+		ModoAutenticacao = "bearer"
+	default:
+		log.Fatalf("AUTH_MODO inválido: %s", modo)
+	}
+
+	if cookieSeguro, erro := strconv.ParseBool(os.Getenv("AUTH_COOKIE_SEGURO")); erro == nil {
+		CookieSeguro = cookieSeguro
+	}
+
+	switch sameSite := os.Getenv("AUTH_COOKIE_SAMESITE"); sameSite {
+	case "strict", "lax", "none":
+		CookieSameSite = sameSite
+	case "":
+		CookieSameSite = "lax"
+	default:
+		log.Fatalf("AUTH_COOKIE_SAMESITE inválido: %s", sameSite)
+	}
+
+	if tamanho, erro := strconv.Atoi(os.Getenv("SENHA_TAMANHO_MINIMO")); erro == nil {
+		SenhaTamanhoMinimo = tamanho
+	}
+
+	if classes, erro := strconv.Atoi(os.Getenv("SENHA_CLASSES_MINIMAS")); erro == nil {
+		SenhaClassesMinimas = classes
+	}
+
+	SenhasVazadasArquivo = os.Getenv("SENHAS_VAZADAS_ARQUIVO")
+
+	switch algoritmo := os.Getenv("HASH_ALGORITMO"); algoritmo {
+	case "argon2id", "bcrypt":
+		HashAlgoritmo = algoritmo
+	case "":
+		HashAlgoritmo = "argon2id"
+	default:
+		log.Fatalf("HASH_ALGORITMO inválido: %s", algoritmo)
+	}
+
+	HashPepper = os.Getenv("HASH_PEPPER")
+
+	if custo, erro := strconv.Atoi(os.Getenv("BCRYPT_CUSTO")); erro == nil {
+		BcryptCusto = custo
+	}
+
+	if memoria, erro := strconv.Atoi(os.Getenv("ARGON2_MEMORIA")); erro == nil {
+		Argon2Memoria = memoria
+	}
+
+	if iteracoes, erro := strconv.Atoi(os.Getenv("ARGON2_ITERACOES")); erro == nil {
+		Argon2Iteracoes = iteracoes
+	}
+
+	if paralelismo, erro := strconv.Atoi(os.Getenv("ARGON2_PARALELISMO")); erro == nil {
+		Argon2Paralelismo = paralelismo
+	}
+
+	// O argon2 entra em pânico com paralelismo ou iterações zerados, e o paralelismo precisa
+	// caber em um byte
+	if Argon2Paralelismo < 1 || Argon2Paralelismo > 255 {
+		log.Fatalf("ARGON2_PARALELISMO inválido: %d, use de 1 a 255", Argon2Paralelismo)
+	}
+	if Argon2Iteracoes < 1 || int64(Argon2Iteracoes) > math.MaxUint32 {
+		log.Fatalf("ARGON2_ITERACOES inválido: %d", Argon2Iteracoes)
+	}
+	if Argon2Memoria < 8*Argon2Paralelismo || int64(Argon2Memoria) > math.MaxUint32 {
+		log.Fatalf("ARGON2_MEMORIA inválido: %d, use ao menos 8 KiB por thread", Argon2Memoria)
+	}
+
+	URLApp = os.Getenv("URL_APP")
+	if URLApp == "" {
+		URLApp = "http://localhost:3000"
+	}
+
+	switch enviador := os.Getenv("EMAIL_ENVIADOR"); enviador {
+	case "log", "arquivo":
+		EmailEnviador = enviador
+	case "":
+		EmailEnviador = "log"
+	default:
+		log.Fatalf("EMAIL_ENVIADOR inválido: %s", enviador)
+	}
+
+	EmailDiretorio = os.Getenv("EMAIL_DIRETORIO")
+	if EmailDiretorio == "" {
+		EmailDiretorio = "emails"
+	}
+
+	URLAPI = strings.TrimSuffix(os.Getenv("URL_API"), "/")
+	if URLAPI == "" {
+		URLAPI = fmt.Sprintf("http://localhost:%d", Porta)
+	}
+
+	switch armazenamento := os.Getenv("MIDIA_ARMAZENAMENTO"); armazenamento {
+	case "local", "s3":
+		MidiaArmazenamento = armazenamento
+	case "":
+		MidiaArmazenamento = "local"
+	default:
+		log.Fatalf("MIDIA_ARMAZENAMENTO inválido: %s", armazenamento)
+	}
+
+	MidiaDiretorio = os.Getenv("MIDIA_DIRETORIO")
+	if MidiaDiretorio == "" {
+		MidiaDiretorio = "midias"
+	}
+
+	if tamanho, erro := strconv.ParseInt(os.Getenv("MIDIA_TAMANHO_MAXIMO"), 10, 64); erro == nil {
+		MidiaTamanhoMaximo = tamanho
+	}
+
+	S3Endpoint = strings.TrimSuffix(os.Getenv("S3_ENDPOINT"), "/")
+	S3Regiao = os.Getenv("S3_REGIAO")
+	if S3Regiao == "" {
+		S3Regiao = "us-east-1"
+	}
+	S3Bucket = os.Getenv("S3_BUCKET")
+	S3ChaveAcesso = os.Getenv("S3_CHAVE_ACESSO")
+	S3ChaveSecreta = os.Getenv("S3_CHAVE_SECRETA")
 }
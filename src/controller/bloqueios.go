@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"api/src/autenticacao"
+	"api/src/banco"
+	"api/src/repositorio"
+	"api/src/respostas"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// BloquearUsuario bloqueia um usuário, desfazendo os seguimentos entre os dois
+func BloquearUsuario(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	bloqueadoID, erro := strconv.ParseUint(mux.Vars(r)["usuarioId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	if bloqueadoID == usuarioID {
+		respostas.Erro(w, http.StatusForbidden, errors.New("Não é possivel bloquear você mesmo"))
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeBloqueios(db)
+	if erro = repositorio.Bloquear(usuarioID, bloqueadoID); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// DesbloquearUsuario desfaz um bloqueio feito pelo usuário logado
+func DesbloquearUsuario(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	bloqueadoID, erro := strconv.ParseUint(mux.Vars(r)["usuarioId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeBloqueios(db)
+	if erro = repositorio.Desbloquear(usuarioID, bloqueadoID); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// BuscarBloqueios lista os usuários bloqueados pelo usuário logado
+func BuscarBloqueios(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeBloqueios(db)
+	usuarios, erro := repositorio.Buscar(usuarioID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusOK, usuarios)
+}
+
+// perfilBloqueado responde 404 quando há bloqueio entre o leitor e o usuário do perfil,
+// como se o perfil não existisse. Retorna true quando a resposta já foi escrita
+func perfilBloqueado(w http.ResponseWriter, db *sql.DB, leitorID, usuarioID uint64) bool {
+	bloqueado, erro := repositorio.NovoRepositorioDeBloqueios(db).ExisteEntre(leitorID, usuarioID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return true
+	}
+
+	if bloqueado {
+		respostas.Erro(w, http.StatusNotFound, errors.New("Usuário não encontrado"))
+		return true
+	}
+	return false
+}
@@ -0,0 +1,13 @@
+package controller
+
+import (
+	"api/src/autenticacao"
+	"api/src/respostas"
+	"net/http"
+)
+
+// BuscarChavesPublicas publica as chaves públicas usadas para validar os tokens (JWKS)
+func BuscarChavesPublicas(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respostas.JSON(w, http.StatusOK, autenticacao.ChavesPublicas())
+}
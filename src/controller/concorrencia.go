@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"api/src/repositorio"
+	"api/src/respostas"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag monta a ETag de um registro com a versão dele no banco e o hash do corpo enviado. A
+// versão serve para o If-Match das alterações; o hash muda também com o que não altera a
+// versão, como curtidas, repostagens e o nick do autor, e serve para o If-None-Match
+func etag(versao uint64, corpo []byte) string {
+	soma := sha256.Sum256(corpo)
+	return `"` + strconv.FormatUint(versao, 10) + "-" + hex.EncodeToString(soma[:8]) + `"`
+}
+
+// versaoDaETag extrai a versão de uma ETag enviada pelo cliente
+func versaoDaETag(valor string) (uint64, bool) {
+	valor = strings.Trim(valor, `"`)
+	if separador := strings.Index(valor, "-"); separador >= 0 {
+		valor = valor[:separador]
+	}
+
+	versao, erro := strconv.ParseUint(valor, 10, 64)
+	return versao, erro == nil
+}
+
+// responderComETag escreve o registro com a sua ETag, ou apenas 304 quando o cliente
+// já tem exatamente esse corpo (If-None-Match)
+func responderComETag(w http.ResponseWriter, r *http.Request, versao uint64, dados interface{}) {
+	corpo, erro := json.Marshal(dados)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	atual := etag(versao, corpo)
+	w.Header().Set("ETag", atual)
+
+	if condicao := r.Header.Get("If-None-Match"); condicao != "" {
+		// If-None-Match usa a comparação fraca, então W/"1-ab" também corresponde a "1-ab"
+		for _, valor := range strings.Split(condicao, ",") {
+			valor = strings.TrimPrefix(strings.TrimSpace(valor), "W/")
+			if valor == "*" || valor == atual {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+	}
+
+	respostas.JSON(w, http.StatusOK, dados)
+}
+
+// versaoEsperada confere o If-Match contra a versão atual do registro. Só a versão da ETag é
+// comparada, então curtidas e outras mudanças que não passam por edição não causam 412.
+// Retorna a versão que a alteração deve exigir no banco (zero quando o cliente não mandou o
+// cabeçalho) e responde 412 quando o cliente está editando uma versão antiga
+func versaoEsperada(w http.ResponseWriter, r *http.Request, versaoAtual uint64) (uint64, bool) {
+	condicao := r.Header.Get("If-Match")
+	if condicao == "" {
+		return 0, true
+	}
+
+	// If-Match usa a comparação forte, então ETags fracas nunca correspondem
+	for _, valor := range strings.Split(condicao, ",") {
+		valor = strings.TrimSpace(valor)
+		if valor == "*" {
+			return versaoAtual, true
+		}
+
+		if strings.HasPrefix(valor, "W/") {
+			continue
+		}
+
+		if versao, ok := versaoDaETag(valor); ok && versao == versaoAtual {
+			return versaoAtual, true
+		}
+	}
+
+	respostas.Erro(w, http.StatusPreconditionFailed, repositorio.ErrVersaoDesatualizada)
+	return 0, false
+}
@@ -0,0 +1,384 @@
+package controller
+
+import (
+	"api/src/autenticacao"
+	"api/src/banco"
+	"api/src/eventos"
+	"api/src/modelos"
+	"api/src/repositorio"
+	"api/src/respostas"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// limiteMensagens é a quantidade de mensagens retornadas por página do histórico
+const limiteMensagens = 50
+
+// BuscarConversas lista as conversas do usuário logado com a última mensagem e as não lidas
+func BuscarConversas(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeConversas(db)
+	conversas, erro := repositorio.Buscar(usuarioID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusOK, conversas)
+}
+
+// CriarConversa abre a conversa com o usuário informado, ou retorna a que já existe
+func CriarConversa(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	body, erro := ioutil.ReadAll(r.Body)
+	if erro != nil {
+		respostas.Erro(w, http.StatusUnprocessableEntity, erro)
+		return
+	}
+
+	var pedido struct {
+		UsuarioID uint64 `json:"usuarioId"`
+	}
+	if erro = json.Unmarshal(body, &pedido); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	if pedido.UsuarioID == 0 || pedido.UsuarioID == usuarioID {
+		erros := modelos.ErroDeValidacao{}
+		erros.Adicionar("usuarioId", "Informe outro usuário para conversar")
+		respostas.Erro(w, http.StatusBadRequest, erros.Erro())
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeConversas(db)
+	pode, erro := repositorio.PodeReceberMensagem(usuarioID, pedido.UsuarioID)
+	if erro != nil {
+		erroNoDestinatario(w, erro)
+		return
+	}
+
+	// A privacidade vale só para abrir a conversa. Quem já conversa segue podendo responder,
+	// mesmo que deixe de seguir ou que a conta do outro passe a ser privada
+	if !pode {
+		existe, erro := repositorio.ExisteDireta(usuarioID, pedido.UsuarioID)
+		if erro != nil {
+			respostas.Erro(w, http.StatusInternalServerError, erro)
+			return
+		}
+		if !existe {
+			respostas.Erro(w, http.StatusForbidden, errors.New("Este usuário só recebe mensagens de seguidores"))
+			return
+		}
+	}
+
+	conversaID, criada, erro := repositorio.BuscarOuCriarDireta(usuarioID, pedido.UsuarioID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	conversa, erro := repositorio.BuscarPorID(conversaID, usuarioID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	status := http.StatusOK
+	if criada {
+		status = http.StatusCreated
+	}
+	respostas.JSON(w, status, conversa)
+}
+
+// BuscarConversa traz uma conversa de que o usuário logado participa
+func BuscarConversa(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	conversaID, erro := strconv.ParseUint(mux.Vars(r)["conversaId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeConversas(db)
+	conversa, erro := repositorio.BuscarPorID(conversaID, usuarioID)
+	if erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusOK, conversa)
+}
+
+// BuscarMensagens traz o histórico da conversa, das mais novas para as mais antigas. Com
+// ?antes={mensagemId} traz a página anterior
+func BuscarMensagens(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	conversaID, erro := strconv.ParseUint(mux.Vars(r)["conversaId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	var antes uint64
+	if valor := r.URL.Query().Get("antes"); valor != "" {
+		if antes, erro = strconv.ParseUint(valor, 10, 64); erro != nil {
+			respostas.Erro(w, http.StatusBadRequest, erro)
+			return
+		}
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeConversas(db)
+	if _, erro = repositorio.BuscarPorID(conversaID, usuarioID); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	mensagens, erro := repositorio.BuscarMensagens(conversaID, usuarioID, antes, limiteMensagens)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusOK, mensagens)
+}
+
+// EnviarMensagem manda uma mensagem na conversa e a entrega em tempo real aos participantes
+func EnviarMensagem(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	conversaID, erro := strconv.ParseUint(mux.Vars(r)["conversaId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	body, erro := ioutil.ReadAll(r.Body)
+	if erro != nil {
+		respostas.Erro(w, http.StatusUnprocessableEntity, erro)
+		return
+	}
+
+	var mensagem modelos.Mensagem
+	if erro = json.Unmarshal(body, &mensagem); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	if erro = mensagem.Preparar(); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	bloqueios := repositorio.NovoRepositorioDeBloqueios(db)
+	repositorio := repositorio.NovoRepositorioDeConversas(db)
+	conversa, erro := repositorio.BuscarPorID(conversaID, usuarioID)
+	if erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	// Nas conversas diretas um bloqueio vale a cada mensagem. A privacidade só é conferida ao
+	// abrir a conversa, e nos grupos as regras são conferidas quando alguém é adicionado
+	if conversa.Tipo == modelos.ConversaDireta {
+		for _, participante := range conversa.Outros(usuarioID) {
+			bloqueado, erro := bloqueios.ExisteEntre(usuarioID, participante.ID)
+			if erro != nil {
+				respostas.Erro(w, http.StatusInternalServerError, erro)
+				return
+			}
+			if bloqueado {
+				respostas.Erro(w, http.StatusNotFound, errors.New("Usuário não encontrado"))
+				return
+			}
+		}
+	}
+
+	mensagem.ConversaID = conversaID
+	mensagem.AutorID = usuarioID
+
+	mensagemID, erro := repositorio.CriarMensagem(mensagem)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	mensagem, erro = repositorio.BuscarMensagem(conversaID, mensagemID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	publicarNaConversa(conversa, eventos.EventoMensagem, mensagem)
+	respostas.JSON(w, http.StatusCreated, mensagem)
+}
+
+// DeletarMensagem apaga uma mensagem do usuário logado para todos os participantes
+func DeletarMensagem(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+	parametros := mux.Vars(r)
+
+	conversaID, erro := strconv.ParseUint(parametros["conversaId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	mensagemID, erro := strconv.ParseUint(parametros["mensagemId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeConversas(db)
+	conversa, erro := repositorio.BuscarPorID(conversaID, usuarioID)
+	if erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	mensagem, erro := repositorio.BuscarMensagem(conversaID, mensagemID)
+	if erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	if mensagem.AutorID != usuarioID || mensagem.Tipo == modelos.MensagemDoSistema {
+		respostas.Erro(w, http.StatusForbidden, errors.New("Não é possível apagar uma mensagem que não é sua"))
+		return
+	}
+
+	if erro = repositorio.DeletarMensagem(conversaID, mensagemID, usuarioID); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	publicarNaConversa(conversa, eventos.EventoMensagemApagada, map[string]uint64{
+		"conversaId": conversaID,
+		"mensagemId": mensagemID,
+	})
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// LerConversa marca as mensagens da conversa como lidas e avisa os outros participantes
+func LerConversa(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	conversaID, erro := strconv.ParseUint(mux.Vars(r)["conversaId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeConversas(db)
+	conversa, erro := repositorio.BuscarPorID(conversaID, usuarioID)
+	if erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	ultimaLidaID, erro := repositorio.MarcarComoLida(conversaID, usuarioID)
+	if erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	publicarNaConversa(conversa, eventos.EventoLeitura, map[string]uint64{
+		"conversaId":   conversaID,
+		"usuarioId":    usuarioID,
+		"ultimaLidaId": ultimaLidaID,
+	})
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// podeMandarMensagem responde 404 quando o destinatário não existe ou há bloqueio entre os
+// dois, e 403 quando a conta dele é privada e o remetente não é seguidor
+func podeMandarMensagem(w http.ResponseWriter, conversas *repositorio.Conversas, remetenteID, destinatarioID uint64) bool {
+	pode, erro := conversas.PodeReceberMensagem(remetenteID, destinatarioID)
+	if erro != nil {
+		erroNoDestinatario(w, erro)
+		return false
+	}
+
+	if !pode {
+		respostas.Erro(w, http.StatusForbidden, errors.New("Este usuário só recebe mensagens de seguidores"))
+		return false
+	}
+	return true
+}
+
+// erroNoDestinatario responde o erro ao conferir o destinatário. Não existir e haver
+// bloqueio dão o mesmo 404, para o bloqueio não ser revelado
+func erroNoDestinatario(w http.ResponseWriter, erro error) {
+	status := statusDoRepositorio(erro)
+	if status == http.StatusNotFound {
+		erro = errors.New("Usuário não encontrado")
+	}
+	respostas.Erro(w, status, erro)
+}
+
+// publicarNaConversa entrega o evento a todos os participantes, inclusive a quem o causou,
+// para as outras conexões dele ficarem em dia
+func publicarNaConversa(conversa modelos.Conversa, tipo string, dados interface{}) {
+	for _, participante := range conversa.Participantes {
+		eventos.Publicar(eventos.TopicoMensagens(participante.ID), tipo, dados)
+	}
+}
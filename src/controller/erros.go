@@ -0,0 +1,21 @@
+package controller
+
+import (
+	"api/src/repositorio"
+	"errors"
+	"net/http"
+)
+
+// statusDoRepositorio escolhe o status da resposta para um erro retornado pelos repositórios
+func statusDoRepositorio(erro error) int {
+	switch {
+	case errors.Is(erro, repositorio.ErrNaoEncontrado):
+		return http.StatusNotFound
+	case errors.Is(erro, repositorio.ErrVersaoDesatualizada):
+		return http.StatusPreconditionFailed
+	case errors.Is(erro, repositorio.ErrUltimoAdmin):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
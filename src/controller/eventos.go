@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"api/src/autenticacao"
+	"api/src/eventos"
+	"api/src/modelos"
+	"api/src/repositorio"
+	"api/src/respostas"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// intervaloDoHeartbeat é de quanto em quanto tempo a conexão recebe um comentário vazio,
+// para proxies não a derrubarem por inatividade
+const intervaloDoHeartbeat = 25 * time.Second
+
+// AcompanharEventos mantém aberta uma conexão Server-Sent Events que recebe, em tempo real,
+// as novas publicações de quem o usuário segue, as notificações, as curtidas nas publicações
+// dele e as mensagens das conversas. O navegador reenvia o Last-Event-ID ao reconectar e os
+// eventos perdidos são entregues
+func AcompanharEventos(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respostas.Erro(w, http.StatusInternalServerError, errors.New("O servidor não suporta streaming"))
+		return
+	}
+
+	var ultimoID uint64
+	if valor := r.Header.Get("Last-Event-ID"); valor != "" {
+		id, erro := strconv.ParseUint(valor, 10, 64)
+		if erro != nil {
+			respostas.Erro(w, http.StatusBadRequest, erro)
+			return
+		}
+		ultimoID = id
+	}
+
+	assinatura, pendentes, completo := eventos.Assinar(ultimoID,
+		eventos.TopicoFeed(usuarioID),
+		eventos.TopicoNotificacoes(usuarioID),
+		eventos.TopicoCurtidas(usuarioID),
+		eventos.TopicoMensagens(usuarioID),
+	)
+	defer assinatura.Cancelar()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if !completo {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", eventos.EventoRessincronia)
+	}
+	for _, evento := range pendentes {
+		if erro := escreverEvento(w, evento); erro != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(intervaloDoHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case evento, aberta := <-assinatura.Eventos:
+			if !aberta {
+				return
+			}
+			if erro := escreverEvento(w, evento); erro != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-heartbeat.C:
+			if _, erro := fmt.Fprint(w, ": heartbeat\n\n"); erro != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// escreverEvento escreve o evento no formato Server-Sent Events
+func escreverEvento(w http.ResponseWriter, evento eventos.Evento) error {
+	dados, erro := json.Marshal(evento.Dados)
+	if erro != nil {
+		return erro
+	}
+
+	_, erro = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", evento.ID, evento.Tipo, dados)
+	return erro
+}
+
+// publicarNovaPublicacao avisa os seguidores do autor que a publicação entrou no feed deles.
+// O evento leva só os ids, o cliente busca a publicação para vê-la com os filtros dele
+func publicarNovaPublicacao(db *sql.DB, publicacao modelos.Publicacao) {
+	leitores, erro := repositorio.NovoRepositoriosDePublicacoes(db).BuscarLeitoresNoFeed(publicacao.ID)
+	if erro != nil {
+		log.Printf("Erro ao publicar a publicação %d: %v", publicacao.ID, erro)
+		return
+	}
+
+	dados := map[string]uint64{"publicacaoId": publicacao.ID, "autorId": publicacao.AutorID}
+	for _, leitorID := range leitores {
+		eventos.Publicar(eventos.TopicoFeed(leitorID), eventos.EventoPublicacao, dados)
+	}
+}
+
+// publicarCurtidas avisa o autor e quem acompanha a publicação do novo total de curtidas
+func publicarCurtidas(db *sql.DB, publicacao modelos.Publicacao) {
+	curtidas, erro := repositorio.NovoRepositoriosDePublicacoes(db).BuscarCurtidas(publicacao.ID)
+	if erro != nil {
+		log.Printf("Erro ao publicar as curtidas da publicação %d: %v", publicacao.ID, erro)
+		return
+	}
+
+	dados := map[string]uint64{"publicacaoId": publicacao.ID, "curtidas": curtidas}
+	eventos.Publicar(eventos.TopicoCurtidas(publicacao.AutorID), eventos.EventoCurtidas, dados)
+	eventos.Publicar(eventos.TopicoPublicacao(publicacao.ID), eventos.EventoCurtidas, dados)
+}
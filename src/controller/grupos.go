@@ -0,0 +1,408 @@
+package controller
+
+import (
+	"api/src/autenticacao"
+	"api/src/banco"
+	"api/src/eventos"
+	"api/src/modelos"
+	"api/src/repositorio"
+	"api/src/respostas"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// CriarGrupo cria uma conversa em grupo com o usuário logado como administrador
+func CriarGrupo(w http.ResponseWriter, r *http.Request) {
+	usuario := autenticacao.UsuarioDaRequisicao(r)
+
+	body, erro := ioutil.ReadAll(r.Body)
+	if erro != nil {
+		respostas.Erro(w, http.StatusUnprocessableEntity, erro)
+		return
+	}
+
+	var grupo modelos.Grupo
+	if erro = json.Unmarshal(body, &grupo); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	if erro = grupo.Preparar(usuario.ID); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeConversas(db)
+
+	// Quem não poderia receber uma mensagem direta do criador também não entra no grupo. A
+	// mensagem não diz o motivo para não revelar bloqueios
+	erros := modelos.ErroDeValidacao{}
+	for _, participante := range grupo.Participantes {
+		pode, erro := repositorio.PodeReceberMensagem(usuario.ID, participante)
+		if erro != nil && statusDoRepositorio(erro) != http.StatusNotFound {
+			respostas.Erro(w, http.StatusInternalServerError, erro)
+			return
+		}
+
+		if erro != nil || !pode {
+			erros.Adicionar("participantes", fmt.Sprintf("Não é possível adicionar o usuário %d", participante))
+		}
+	}
+	if erro = erros.Erro(); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	conversaID, erro := repositorio.CriarGrupo(grupo, usuario.ID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	conversa, erro := repositorio.BuscarPorID(conversaID, usuario.ID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	texto := fmt.Sprintf("%s criou o grupo %s", usuario.Nick, grupo.Nome)
+	if erro = registrarNoGrupo(repositorio, conversa, usuario.ID, texto); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	if conversa, erro = repositorio.BuscarPorID(conversaID, usuario.ID); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusCreated, conversa)
+}
+
+// AdicionarMembro coloca um usuário no grupo. Só administradores podem adicionar
+func AdicionarMembro(w http.ResponseWriter, r *http.Request) {
+	usuario := autenticacao.UsuarioDaRequisicao(r)
+
+	conversaID, erro := strconv.ParseUint(mux.Vars(r)["conversaId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	body, erro := ioutil.ReadAll(r.Body)
+	if erro != nil {
+		respostas.Erro(w, http.StatusUnprocessableEntity, erro)
+		return
+	}
+
+	var pedido struct {
+		UsuarioID uint64 `json:"usuarioId"`
+	}
+	if erro = json.Unmarshal(body, &pedido); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeConversas(db)
+	conversa, ok := grupoDoAdmin(w, repositorio, conversaID, usuario.ID)
+	if !ok {
+		return
+	}
+
+	if _, participa := conversa.Participa(pedido.UsuarioID); participa {
+		respostas.JSON(w, http.StatusNoContent, nil)
+		return
+	}
+
+	if len(conversa.Participantes) >= modelos.MaximoMembrosGrupo {
+		respostas.Erro(w, http.StatusConflict,
+			fmt.Errorf("Um grupo pode ter no máximo %d participantes", modelos.MaximoMembrosGrupo))
+		return
+	}
+
+	if !podeMandarMensagem(w, repositorio, usuario.ID, pedido.UsuarioID) {
+		return
+	}
+
+	adicionado, erro := repositorio.AdicionarMembro(conversaID, pedido.UsuarioID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	if adicionado {
+		if conversa, erro = repositorio.BuscarPorID(conversaID, usuario.ID); erro != nil {
+			respostas.Erro(w, http.StatusInternalServerError, erro)
+			return
+		}
+
+		membro, _ := conversa.Participa(pedido.UsuarioID)
+		texto := fmt.Sprintf("%s adicionou %s", usuario.Nick, membro.Nick)
+		if erro = registrarNoGrupo(repositorio, conversa, usuario.ID, texto); erro != nil {
+			respostas.Erro(w, http.StatusInternalServerError, erro)
+			return
+		}
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// RemoverMembro tira um usuário do grupo. Só administradores podem remover; para sair do
+// grupo o próprio usuário usa SairDoGrupo
+func RemoverMembro(w http.ResponseWriter, r *http.Request) {
+	usuario := autenticacao.UsuarioDaRequisicao(r)
+	parametros := mux.Vars(r)
+
+	conversaID, erro := strconv.ParseUint(parametros["conversaId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	membroID, erro := strconv.ParseUint(parametros["usuarioId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	if membroID == usuario.ID {
+		respostas.Erro(w, http.StatusBadRequest, errors.New("Para sair do grupo use /conversas/{conversaId}/sair"))
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeConversas(db)
+	conversa, ok := grupoDoAdmin(w, repositorio, conversaID, usuario.ID)
+	if !ok {
+		return
+	}
+
+	membro, participa := conversa.Participa(membroID)
+	if !participa {
+		respostas.Erro(w, http.StatusNotFound, errors.New("O usuário não participa do grupo"))
+		return
+	}
+
+	promovidoID, erro := repositorio.RemoverMembro(conversaID, membroID)
+	if erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	// Quem foi removido também recebe o aviso, já que a conversa veio de antes da remoção
+	texto := fmt.Sprintf("%s removeu %s", usuario.Nick, membro.Nick)
+	if erro = registrarNoGrupo(repositorio, conversa, usuario.ID, texto); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	if erro = registrarPromocao(repositorio, conversa, usuario.ID, promovidoID); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// SairDoGrupo tira o usuário logado do grupo
+func SairDoGrupo(w http.ResponseWriter, r *http.Request) {
+	usuario := autenticacao.UsuarioDaRequisicao(r)
+
+	conversaID, erro := strconv.ParseUint(mux.Vars(r)["conversaId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeConversas(db)
+	conversa, erro := repositorio.BuscarPorID(conversaID, usuario.ID)
+	if erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	if conversa.Tipo != modelos.ConversaGrupo {
+		respostas.Erro(w, http.StatusBadRequest, errors.New("Só é possível sair de grupos"))
+		return
+	}
+
+	promovidoID, erro := repositorio.RemoverMembro(conversaID, usuario.ID)
+	if erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	// O último a sair apaga o grupo, então não há mais quem avisar
+	if len(conversa.Participantes) > 1 {
+		texto := fmt.Sprintf("%s saiu do grupo", usuario.Nick)
+		if erro = registrarNoGrupo(repositorio, conversa, usuario.ID, texto); erro != nil {
+			respostas.Erro(w, http.StatusInternalServerError, erro)
+			return
+		}
+
+		if erro = registrarPromocao(repositorio, conversa, usuario.ID, promovidoID); erro != nil {
+			respostas.Erro(w, http.StatusInternalServerError, erro)
+			return
+		}
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// TornarAdmin dá a um membro do grupo a função de administrador
+func TornarAdmin(w http.ResponseWriter, r *http.Request) {
+	definirAdmin(w, r, true)
+}
+
+// RemoverAdmin tira de um membro do grupo a função de administrador. O grupo precisa ficar
+// com pelo menos um administrador
+func RemoverAdmin(w http.ResponseWriter, r *http.Request) {
+	definirAdmin(w, r, false)
+}
+
+func definirAdmin(w http.ResponseWriter, r *http.Request, admin bool) {
+	usuario := autenticacao.UsuarioDaRequisicao(r)
+	parametros := mux.Vars(r)
+
+	conversaID, erro := strconv.ParseUint(parametros["conversaId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	membroID, erro := strconv.ParseUint(parametros["usuarioId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeConversas(db)
+	conversa, ok := grupoDoAdmin(w, repositorio, conversaID, usuario.ID)
+	if !ok {
+		return
+	}
+
+	membro, participa := conversa.Participa(membroID)
+	if !participa {
+		respostas.Erro(w, http.StatusNotFound, errors.New("O usuário não participa do grupo"))
+		return
+	}
+
+	if membro.Admin == admin {
+		respostas.JSON(w, http.StatusNoContent, nil)
+		return
+	}
+
+	// A regra de manter um administrador é conferida dentro da alteração, já que a conversa
+	// buscada acima pode estar desatualizada
+	if erro = repositorio.DefinirAdmin(conversaID, membroID, admin); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	texto := fmt.Sprintf("%s tornou %s administrador", usuario.Nick, membro.Nick)
+	if !admin {
+		texto = fmt.Sprintf("%s deixou de ser administrador", membro.Nick)
+	}
+	if erro = registrarNoGrupo(repositorio, conversa, usuario.ID, texto); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// grupoDoAdmin busca o grupo e confere se o usuário é administrador dele. Responde 404 quando
+// ele não participa, 400 para conversas diretas e 403 para quem não é administrador
+func grupoDoAdmin(w http.ResponseWriter, conversas *repositorio.Conversas, conversaID, usuarioID uint64) (modelos.Conversa, bool) {
+	conversa, erro := conversas.BuscarPorID(conversaID, usuarioID)
+	if erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return modelos.Conversa{}, false
+	}
+
+	if conversa.Tipo != modelos.ConversaGrupo {
+		respostas.Erro(w, http.StatusBadRequest, errors.New("Conversas diretas não têm membros para gerenciar"))
+		return modelos.Conversa{}, false
+	}
+
+	if participante, _ := conversa.Participa(usuarioID); !participante.Admin {
+		respostas.Erro(w, http.StatusForbidden, errors.New("Só administradores podem gerenciar os membros do grupo"))
+		return modelos.Conversa{}, false
+	}
+	return conversa, true
+}
+
+// registrarPromocao avisa no grupo quem virou administrador por ter saído o último. Não faz
+// nada quando ninguém foi promovido
+func registrarPromocao(conversas *repositorio.Conversas, conversa modelos.Conversa, autorID, promovidoID uint64) error {
+	if promovidoID == 0 {
+		return nil
+	}
+
+	promovido, _ := conversa.Participa(promovidoID)
+	texto := fmt.Sprintf("%s agora é administrador", promovido.Nick)
+	return registrarNoGrupo(conversas, conversa, autorID, texto)
+}
+
+// registrarNoGrupo grava uma mensagem do sistema sobre uma mudança de membros e a entrega aos
+// participantes da conversa informada
+func registrarNoGrupo(conversas *repositorio.Conversas, conversa modelos.Conversa, autorID uint64, texto string) error {
+	mensagemID, erro := conversas.CriarMensagem(modelos.Mensagem{
+		ConversaID: conversa.ID,
+		Tipo:       modelos.MensagemDoSistema,
+		AutorID:    autorID,
+		Conteudo:   texto,
+	})
+	if erro != nil {
+		return erro
+	}
+
+	mensagem, erro := conversas.BuscarMensagem(conversa.ID, mensagemID)
+	if erro != nil {
+		return erro
+	}
+
+	publicarNaConversa(conversa, eventos.EventoMensagem, mensagem)
+	return nil
+}
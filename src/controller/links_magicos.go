@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"api/src/banco"
+	"api/src/config"
+	"api/src/email"
+	"api/src/modelos"
+	"api/src/repositorio"
+	"api/src/respostas"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// validadeLinkMagico é quanto tempo o link enviado por email pode ser usado
+const validadeLinkMagico = 15 * time.Minute
+
+// SolicitarLinkMagico envia por email um link de login de uso único. A resposta é a mesma
+// exista ou não uma conta com o email, para não revelar quem está cadastrado
+func SolicitarLinkMagico(w http.ResponseWriter, r *http.Request) {
+	body, erro := ioutil.ReadAll(r.Body)
+	if erro != nil {
+		respostas.Erro(w, http.StatusUnprocessableEntity, erro)
+		return
+	}
+
+	var pedido modelos.LinkMagico
+	if erro = json.Unmarshal(body, &pedido); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	enderecoEmail := strings.ToLower(strings.TrimSpace(pedido.Email))
+	if !strings.Contains(enderecoEmail, "@") {
+		respostas.Erro(w, http.StatusBadRequest, errors.New("Email não é válido"))
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	usuario, erro := repositorio.NovoRepositoriosDeUsuarios(db).BuscarPorLogin(enderecoEmail)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	if usuario.ID == 0 {
+		respostas.JSON(w, http.StatusAccepted, nil)
+		return
+	}
+
+	bytes := make([]byte, 32)
+	if _, erro = rand.Read(bytes); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(bytes)
+
+	repositorio := repositorio.NovoRepositorioDeLinksMagicos(db)
+	if erro = repositorio.Criar(hashDoLinkMagico(token), usuario.ID, time.Now().Add(validadeLinkMagico)); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	email.EnviarEmSegundoPlano(email.Mensagem{
+		Para:    usuario.Email,
+		Assunto: "Seu link de acesso ao DevBook",
+		Corpo: fmt.Sprintf(
+			"Olá %s,\n\nUse o link abaixo para entrar no DevBook. Ele vale por %d minutos e só pode ser usado uma vez.\n\n%s/login/link?token=%s\n\n"+
+				"Se você não pediu esse link, ignore este email.",
+			usuario.Nick, int(validadeLinkMagico.Minutes()), config.URLApp, url.QueryEscape(token),
+		),
+	})
+
+	respostas.JSON(w, http.StatusAccepted, nil)
+}
+
+// ConsumirLinkMagico troca o token do link por um token de acesso da API
+func ConsumirLinkMagico(w http.ResponseWriter, r *http.Request) {
+	body, erro := ioutil.ReadAll(r.Body)
+	if erro != nil {
+		respostas.Erro(w, http.StatusUnprocessableEntity, erro)
+		return
+	}
+
+	var pedido modelos.LinkMagico
+	if erro = json.Unmarshal(body, &pedido); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	if pedido.Token == "" {
+		respostas.Erro(w, http.StatusBadRequest, errors.New("O token não pode estar em branco"))
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	usuarioID, erro := repositorio.NovoRepositorioDeLinksMagicos(db).Consumir(hashDoLinkMagico(pedido.Token))
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	if usuarioID == 0 {
+		respostas.Erro(w, http.StatusUnauthorized, errors.New("Link inválido ou expirado"))
+		return
+	}
+
+	usuario, erro := repositorio.NovoRepositoriosDeUsuarios(db).BuscarPorID(usuarioID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	token, erro := iniciarSessao(db, r, usuario)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	responderLogin(w, token)
+}
+
+// hashDoLinkMagico é o que fica salvo no banco, assim um vazamento da tabela não permite login
+func hashDoLinkMagico(token string) string {
+	soma := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(soma[:])
+}
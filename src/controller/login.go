@@ -9,6 +9,7 @@ import (
 	"api/src/seguranca"
 	"encoding/json"
 	"io/ioutil"
+	"log"
 	"net/http"
 )
 
@@ -20,8 +21,8 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var usuario modelos.Usuario
-	if erro := json.Unmarshal(body, &usuario); erro != nil {
+	var credenciais modelos.Credenciais
+	if erro := json.Unmarshal(body, &credenciais); erro != nil {
 		respostas.Erro(w, http.StatusBadRequest, erro)
 		return
 	}
@@ -35,25 +36,80 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	defer db.Close()
 
 	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
-	usuarioSalvoNoBanco, erro := repositorio.BuscaPorEmail(usuario.Email)
+	usuarioSalvoNoBanco, erro := repositorio.BuscarPorLogin(credenciais.Identificador())
 
 	if erro != nil {
 		respostas.Erro(w, http.StatusInternalServerError, erro)
 		return
 	}
 
-	if erro = seguranca.VerificarSenha(usuarioSalvoNoBanco.Senha, usuario.Senha); erro != nil {
+	precisaRehash, erro := seguranca.Verificar(usuarioSalvoNoBanco.Senha, credenciais.Senha)
+	if erro != nil {
 		respostas.Erro(w, http.StatusUnauthorized, erro)
 		return
 	}
 
-	token, erro := autenticacao.CriarToken(usuarioSalvoNoBanco.ID)
+	if precisaRehash {
+		atualizarHashDaSenha(repositorio, usuarioSalvoNoBanco.ID, credenciais.Senha)
+	}
+
+	token, erro := iniciarSessao(db, r, usuarioSalvoNoBanco)
 
 	if erro != nil {
 		respostas.Erro(w, http.StatusInternalServerError, erro)
 		return
 	}
 
-	w.Write([]byte(token))
+	responderLogin(w, token)
+}
+
+// Logout encerra a sessão atual e apaga os cookies de autenticação
+func Logout(w http.ResponseWriter, r *http.Request) {
+	usuario := autenticacao.UsuarioDaRequisicao(r)
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
 
+	repositorio := repositorio.NovoRepositorioDeSessoes(db)
+	if _, erro = repositorio.Revogar(usuario.TokenID, usuario.ID); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	autenticacao.RemoverCookies(w)
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// atualizarHashDaSenha refaz o hash com o algoritmo e parâmetros atuais. Falhas não
+// impedem o login, o hash antigo continua válido e será refeito na próxima tentativa
+func atualizarHashDaSenha(repositorio *repositorio.Usuarios, usuarioID uint64, senha string) {
+	senhaComHash, erro := seguranca.Hash(senha)
+	if erro == nil {
+		erro = repositorio.AtualizarSenha(usuarioID, string(senhaComHash))
+	}
+
+	if erro != nil {
+		log.Printf("\nFalha ao refazer o hash da senha do usuário %d: %v", usuarioID, erro)
+	}
+}
+
+// responderLogin entrega o token no corpo e/ou nos cookies, conforme o modo de autenticação
+func responderLogin(w http.ResponseWriter, token string) {
+	if autenticacao.AceitaCookie() {
+		if erro := autenticacao.DefinirCookies(w, token); erro != nil {
+			respostas.Erro(w, http.StatusInternalServerError, erro)
+			return
+		}
+	}
+
+	if !autenticacao.AceitaBearer() {
+		respostas.JSON(w, http.StatusNoContent, nil)
+		return
+	}
+
+	w.Write([]byte(token))
 }
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"api/src/modelos"
+	"api/src/notificacoes"
+	"api/src/repositorio"
+	"database/sql"
+	"errors"
+)
+
+// vincularMencoes salva as menções da publicação e avisa quem foi mencionado pela primeira
+// vez. Mencionados que não podem ver a publicação não são avisados
+func vincularMencoes(db *sql.DB, publicacao modelos.Publicacao) ([]modelos.Mencao, error) {
+	publicacoes := repositorio.NovoRepositoriosDePublicacoes(db)
+
+	mencoes, novos, erro := publicacoes.VincularMencoes(publicacao.ID, publicacao.AutorID, publicacao.ExtrairMencoes())
+	if erro != nil {
+		return nil, erro
+	}
+
+	if erro = notificarMencoes(db, publicacao, novos); erro != nil {
+		return nil, erro
+	}
+	return mencoes, nil
+}
+
+// notificarMencoes avisa os usuários mencionados pela primeira vez que podem ver a publicação
+func notificarMencoes(db *sql.DB, publicacao modelos.Publicacao, novos []uint64) error {
+	publicacoes := repositorio.NovoRepositoriosDePublicacoes(db)
+
+	for _, usuarioID := range novos {
+		_, erro := publicacoes.BuscarPorID(publicacao.ID, usuarioID)
+		if errors.Is(erro, repositorio.ErrNaoEncontrado) {
+			continue
+		}
+		if erro != nil {
+			return erro
+		}
+
+		publicacaoID := publicacao.ID
+		notificacoes.Emitir(db, modelos.Notificacao{
+			UsuarioID:    usuarioID,
+			Tipo:         modelos.NotificacaoMencao,
+			PublicacaoID: &publicacaoID,
+			AtorID:       publicacao.AutorID,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package controller
+
+import (
+	"api/src/autenticacao"
+	"api/src/banco"
+	"api/src/config"
+	"api/src/midia"
+	"api/src/modelos"
+	"api/src/repositorio"
+	"api/src/respostas"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// EnviarMidia recebe uma imagem em um formulário multipart (campo arquivo) e guarda as suas variantes
+func EnviarMidia(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	novaMidia, status, erro := receberImagem(w, r, db, usuarioID)
+	if erro != nil {
+		respostas.Erro(w, status, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusCreated, novaMidia)
+}
+
+// BuscarMidia traz os dados de uma mídia e os endereços das suas variantes
+func BuscarMidia(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+	parametros := mux.Vars(r)
+	midiaID, erro := strconv.ParseUint(parametros["midiaId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	midiaSalva, ok := midiaVisivel(w, db, midiaID, usuarioID)
+	if !ok {
+		return
+	}
+
+	midiaSalva.URLs = urlsDaMidia(midiaSalva.ID)
+	respostas.JSON(w, http.StatusOK, midiaSalva)
+}
+
+// BaixarMidia entrega o arquivo de uma variante da mídia para quem pode vê-la
+func BaixarMidia(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+	parametros := mux.Vars(r)
+	midiaID, erro := strconv.ParseUint(parametros["midiaId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	variante := parametros["variante"]
+	if _, miniatura := midia.Miniaturas[variante]; !miniatura && variante != midia.VarianteOriginal {
+		respostas.Erro(w, http.StatusNotFound, errors.New("Variante não encontrada"))
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	midiaSalva, ok := midiaVisivel(w, db, midiaID, usuarioID)
+	if !ok {
+		return
+	}
+
+	arquivo, erro := midia.Abrir(midiaSalva.Hash, variante, midiaSalva.Extensao)
+	if errors.Is(erro, midia.ErrNaoEncontrado) {
+		respostas.Erro(w, http.StatusNotFound, erro)
+		return
+	}
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer arquivo.Close()
+
+	// O conteúdo de uma variante nunca muda, mas quem pode vê-la muda, então só o navegador
+	// do usuário guarda a resposta e por pouco tempo
+	w.Header().Set("Content-Type", midia.TipoDaExtensao(midia.ExtensaoDaVariante(midiaSalva.Extensao, variante)))
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, erro = io.Copy(w, arquivo); erro != nil {
+		log.Printf("\nFalha ao enviar a mídia %d: %v", midiaID, erro)
+	}
+}
+
+// DeletarMidia remove uma mídia do usuário logado
+func DeletarMidia(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+	parametros := mux.Vars(r)
+
+	midiaID, erro := strconv.ParseUint(parametros["midiaId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeMidias(db)
+	midiaSalva, erro := repositorio.BuscarPorID(midiaID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	if midiaSalva.ID == 0 {
+		respostas.Erro(w, http.StatusNotFound, errors.New("Mídia não encontrada"))
+		return
+	}
+
+	if midiaSalva.UsuarioID != usuarioID {
+		respostas.Erro(w, http.StatusForbidden, errors.New("Não é possivel deletar uma mídia que não é sua"))
+		return
+	}
+
+	// A contagem de quem ainda usa o conteúdo e a remoção dos arquivos acontecem sob a mesma
+	// trava que um envio do mesmo conteúdo segura
+	destravar := midia.Travar(midiaSalva.Hash)
+	defer destravar()
+
+	emUso, erro := repositorio.Deletar(midiaSalva)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	// O arquivo é compartilhado por todas as mídias com o mesmo conteúdo
+	if !emUso {
+		if erro = midia.Apagar(midiaSalva.Hash, midiaSalva.Extensao); erro != nil {
+			log.Printf("\nFalha ao apagar os arquivos da mídia %d: %v", midiaID, erro)
+		}
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// AtualizarAvatar recebe uma imagem e a usa como avatar do usuário logado
+func AtualizarAvatar(w http.ResponseWriter, r *http.Request) {
+	usuarioIDNoToken := autenticacao.UsuarioDaRequisicao(r).ID
+	parametros := mux.Vars(r)
+
+	usuarioID, erro := strconv.ParseUint(parametros["usuarioId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	if usuarioID != usuarioIDNoToken {
+		respostas.Erro(w, http.StatusForbidden, errors.New("Não tem autorização de alterar usuário que não é seu"))
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	midiaSalva, status, erro := receberImagem(w, r, db, usuarioID)
+	if erro != nil {
+		respostas.Erro(w, status, erro)
+		return
+	}
+
+	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
+	if erro = repositorio.AtualizarAvatar(usuarioID, midiaSalva.URLs["media"]); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusCreated, midiaSalva)
+}
+
+// receberImagem lê o arquivo do formulário, processa, guarda e registra a mídia no banco
+func receberImagem(w http.ResponseWriter, r *http.Request, db *sql.DB, usuarioID uint64) (modelos.Midia, int, error) {
+	// Folga para os cabeçalhos e demais campos do multipart
+	r.Body = http.MaxBytesReader(w, r.Body, config.MidiaTamanhoMaximo+64<<10)
+
+	arquivo, _, erro := r.FormFile("arquivo")
+	if erro != nil {
+		var muitoGrande *http.MaxBytesError
+		if errors.As(erro, &muitoGrande) {
+			return modelos.Midia{}, http.StatusRequestEntityTooLarge, tamanhoExcedido()
+		}
+		return modelos.Midia{}, http.StatusBadRequest, errors.New("Envie a imagem no campo arquivo de um formulário multipart")
+	}
+	defer arquivo.Close()
+
+	conteudo, erro := ioutil.ReadAll(io.LimitReader(arquivo, config.MidiaTamanhoMaximo+1))
+	if erro != nil {
+		return modelos.Midia{}, http.StatusUnprocessableEntity, erro
+	}
+
+	if int64(len(conteudo)) > config.MidiaTamanhoMaximo {
+		return modelos.Midia{}, http.StatusRequestEntityTooLarge, tamanhoExcedido()
+	}
+
+	imagem, erro := midia.Processar(conteudo)
+	if errors.Is(erro, midia.ErrFormatoNaoSuportado) {
+		return modelos.Midia{}, http.StatusUnsupportedMediaType, erro
+	}
+	if erro != nil {
+		return modelos.Midia{}, http.StatusUnprocessableEntity, erro
+	}
+
+	// Segura o hash até a mídia ser registrada, para uma exclusão do mesmo conteúdo não apagar
+	// os arquivos no meio do caminho
+	destravar := midia.Travar(imagem.Hash)
+	defer destravar()
+
+	if erro = midia.Guardar(imagem); erro != nil {
+		return modelos.Midia{}, http.StatusInternalServerError, erro
+	}
+
+	novaMidia := modelos.Midia{
+		UsuarioID: usuarioID,
+		Hash:      imagem.Hash,
+		Tipo:      imagem.Tipo,
+		Extensao:  imagem.Extensao,
+		Largura:   imagem.Largura,
+		Altura:    imagem.Altura,
+		Tamanho:   len(imagem.Variantes[midia.VarianteOriginal]),
+	}
+
+	novaMidia.ID, erro = repositorio.NovoRepositorioDeMidias(db).Criar(novaMidia)
+	if erro != nil {
+		return modelos.Midia{}, http.StatusInternalServerError, erro
+	}
+
+	novaMidia.URLs = urlsDaMidia(novaMidia.ID)
+	return novaMidia, http.StatusCreated, nil
+}
+
+func urlsDaMidia(midiaID uint64) map[string]string {
+	urls := map[string]string{
+		midia.VarianteOriginal: fmt.Sprintf("%s/midias/%d/%s", config.URLAPI, midiaID, midia.VarianteOriginal),
+	}
+	for variante := range midia.Miniaturas {
+		urls[variante] = fmt.Sprintf("%s/midias/%d/%s", config.URLAPI, midiaID, variante)
+	}
+	return urls
+}
+
+func tamanhoExcedido() error {
+	return fmt.Errorf("O arquivo deve ter no máximo %d bytes", config.MidiaTamanhoMaximo)
+}
+
+// midiaVisivel busca a mídia e confere se o usuário pode vê-la. Mídias que não existem e as
+// que ele não pode ver respondem 404, para não revelar quais ids existem
+func midiaVisivel(w http.ResponseWriter, db *sql.DB, midiaID, usuarioID uint64) (modelos.Midia, bool) {
+	midias := repositorio.NovoRepositorioDeMidias(db)
+
+	midiaSalva, erro := midias.BuscarPorID(midiaID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return modelos.Midia{}, false
+	}
+
+	pode := false
+	if midiaSalva.ID != 0 {
+		if pode, erro = midias.PodeVer(midiaID, usuarioID); erro != nil {
+			respostas.Erro(w, http.StatusInternalServerError, erro)
+			return modelos.Midia{}, false
+		}
+	}
+
+	if !pode {
+		respostas.Erro(w, http.StatusNotFound, errors.New("Mídia não encontrada"))
+		return modelos.Midia{}, false
+	}
+	return midiaSalva, true
+}
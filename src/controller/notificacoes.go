@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"api/src/autenticacao"
+	"api/src/banco"
+	"api/src/modelos"
+	"api/src/repositorio"
+	"api/src/respostas"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// limiteNotificacoes é a quantidade máxima de notificações retornadas por busca
+const limiteNotificacoes = 50
+
+// BuscarNotificacoes lista as notificações do usuário logado. Com nao-lidas=true traz só as não lidas
+func BuscarNotificacoes(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+	apenasNaoLidas := r.URL.Query().Get("nao-lidas") == "true"
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeNotificacoes(db)
+	notificacoes, erro := repositorio.Buscar(usuarioID, apenasNaoLidas, limiteNotificacoes)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusOK, notificacoes)
+}
+
+// ContarNotificacoesNaoLidas retorna quantas notificações o usuário logado ainda não leu
+func ContarNotificacoesNaoLidas(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeNotificacoes(db)
+	total, erro := repositorio.ContarNaoLidas(usuarioID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusOK, struct {
+		NaoLidas uint64 `json:"naoLidas"`
+	}{total})
+}
+
+// LerNotificacao marca uma notificação do usuário logado como lida
+func LerNotificacao(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	notificacaoID, erro := strconv.ParseUint(mux.Vars(r)["notificacaoId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeNotificacoes(db)
+	if erro = repositorio.MarcarComoLida(notificacaoID, usuarioID); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// LerTodasNotificacoes marca todas as notificações do usuário logado como lidas
+func LerTodasNotificacoes(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeNotificacoes(db)
+	if erro = repositorio.MarcarTodasComoLidas(usuarioID); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// BuscarPreferenciasDeNotificacao traz quais tipos de notificação o usuário logado recebe
+func BuscarPreferenciasDeNotificacao(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeNotificacoes(db)
+	preferencias, erro := repositorio.BuscarPreferencias(usuarioID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusOK, preferencias)
+}
+
+// AtualizarPreferenciasDeNotificacao liga ou desliga tipos de notificação, como {"curtida": false}.
+// Tipos que não vêm no corpo continuam como estavam
+func AtualizarPreferenciasDeNotificacao(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	body, erro := ioutil.ReadAll(r.Body)
+	if erro != nil {
+		respostas.Erro(w, http.StatusUnprocessableEntity, erro)
+		return
+	}
+
+	var preferencias map[string]bool
+	if erro = json.Unmarshal(body, &preferencias); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	erros := modelos.ErroDeValidacao{}
+	for tipo := range preferencias {
+		if !modelos.TipoDeNotificacaoValido(tipo) {
+			erros.Adicionar(tipo, fmt.Sprintf("Tipo de notificação desconhecido: %s", tipo))
+		}
+	}
+	if erro = erros.Erro(); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeNotificacoes(db)
+	if erro = repositorio.SalvarPreferencias(usuarioID, preferencias); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
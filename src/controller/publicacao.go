@@ -4,8 +4,10 @@ import (
 	"api/src/autenticacao"
 	"api/src/banco"
 	"api/src/modelos"
+	"api/src/notificacoes"
 	"api/src/repositorio"
 	"api/src/respostas"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
@@ -17,11 +19,7 @@ import (
 
 // CriarPublicacao cria uma nova publicacao no banco de dados
 func CriarPublicacao(w http.ResponseWriter, r *http.Request) {
-	usuarioID, erro := autenticacao.ExtrairUsuarioID(r)
-	if erro != nil {
-		respostas.Erro(w, http.StatusUnauthorized, erro)
-		return
-	}
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
 
 	body, erro := ioutil.ReadAll(r.Body)
 	if erro != nil {
@@ -48,24 +46,49 @@ func CriarPublicacao(w http.ResponseWriter, r *http.Request) {
 		respostas.Erro(w, http.StatusInternalServerError, erro)
 		return
 	}
+	defer db.Close()
+
+	if status, erro := validarMidias(db, publicacao.Midias, usuarioID); erro != nil {
+		respostas.Erro(w, status, erro)
+		return
+	}
 
 	repositorio := repositorio.NovoRepositoriosDePublicacoes(db)
 
-	publicacao.ID, erro = repositorio.Criar(publicacao)
+	// Só dá para citar publicações que o autor consegue ver
+	if publicacao.CitacaoID != nil {
+		_, erro = repositorio.BuscarPorID(*publicacao.CitacaoID, usuarioID)
+		if erro != nil && statusDoRepositorio(erro) == http.StatusNotFound {
+			erros := modelos.ErroDeValidacao{}
+			erros.Adicionar("citacaoId", "A publicação citada não foi encontrada")
+			respostas.Erro(w, http.StatusBadRequest, erros.Erro())
+			return
+		}
+		if erro != nil {
+			respostas.Erro(w, http.StatusInternalServerError, erro)
+			return
+		}
+	}
+
+	var novos []uint64
+	publicacao.ID, publicacao.Mencoes, novos, erro = repositorio.CriarComVinculos(publicacao)
 	if erro != nil {
 		respostas.Erro(w, http.StatusInternalServerError, erro)
 		return
 	}
+
+	if erro = notificarMencoes(db, publicacao, novos); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	publicarNovaPublicacao(db, publicacao)
 	respostas.JSON(w, http.StatusCreated, publicacao)
 }
 
 // BuscarPublicacoes trás todas as publicacao no banco de dados
 func BuscarPublicacoes(w http.ResponseWriter, r *http.Request) {
-	usuarioId, erro := autenticacao.ExtrairUsuarioID(r)
-	if erro != nil {
-		respostas.Erro(w, http.StatusUnauthorized, erro)
-		return
-	}
+	usuarioId := autenticacao.UsuarioDaRequisicao(r).ID
 
 	db, erro := banco.Conectar()
 	if erro != nil {
@@ -85,6 +108,7 @@ func BuscarPublicacoes(w http.ResponseWriter, r *http.Request) {
 
 // BuscarPublicacao busca uma publicacao no banco de dados
 func BuscarPublicacao(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
 	parametros := mux.Vars(r)
 	publicacaoId, erro := strconv.ParseUint(parametros["publicacaoId"], 10, 64)
 
@@ -101,23 +125,19 @@ func BuscarPublicacao(w http.ResponseWriter, r *http.Request) {
 	defer db.Close()
 
 	repositorio := repositorio.NovoRepositoriosDePublicacoes(db)
-	publicacao, erro := repositorio.BuscarPorID(publicacaoId)
+	publicacao, erro := repositorio.BuscarPorID(publicacaoId, usuarioID)
 	if erro != nil {
-		respostas.Erro(w, http.StatusInternalServerError, erro)
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
 		return
 	}
 
-	respostas.JSON(w, http.StatusOK, publicacao)
+	responderComETag(w, r, publicacao.Versao, publicacao)
 
 }
 
 // AtualizarPublicacao atualiza uma publicacao no banco de dados
 func AtualizarPublicacao(w http.ResponseWriter, r *http.Request) {
-	usuarioID, erro := autenticacao.ExtrairUsuarioID(r)
-	if erro != nil {
-		respostas.Erro(w, http.StatusUnauthorized, erro)
-		return
-	}
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
 	parametros := mux.Vars(r)
 
 	publicacaoId, erro := strconv.ParseUint(parametros["publicacaoId"], 10, 64)
@@ -134,9 +154,9 @@ func AtualizarPublicacao(w http.ResponseWriter, r *http.Request) {
 	defer db.Close()
 
 	repositorio := repositorio.NovoRepositoriosDePublicacoes(db)
-	publicacaoSalvaNoBanco, erro := repositorio.BuscarPorID(publicacaoId)
+	publicacaoSalvaNoBanco, erro := repositorio.BuscarPorID(publicacaoId, usuarioID)
 	if erro != nil {
-		respostas.Erro(w, http.StatusBadRequest, erro)
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
 		return
 	}
 
@@ -145,6 +165,11 @@ func AtualizarPublicacao(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	versao, ok := versaoEsperada(w, r, publicacaoSalvaNoBanco.Versao)
+	if !ok {
+		return
+	}
+
 	body, erro := ioutil.ReadAll(r.Body)
 	if erro != nil {
 		respostas.Erro(w, http.StatusUnprocessableEntity, erro)
@@ -163,21 +188,126 @@ func AtualizarPublicacao(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if erro = repositorio.Atualizar(publicacaoId, publicacao); erro != nil {
-		respostas.Erro(w, http.StatusBadRequest, erro)
+	if status, erro := validarMidias(db, publicacao.Midias, usuarioID); erro != nil {
+		respostas.Erro(w, status, erro)
+		return
+	}
+
+	if erro = repositorio.Atualizar(publicacaoId, publicacao, versao); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	if erro = repositorio.VincularTags(publicacaoId, publicacao.Tags); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	publicacao.ID, publicacao.AutorID = publicacaoId, usuarioID
+	if _, erro = vincularMencoes(db, publicacao); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	// O PUT substitui a publicação inteira, então sem o campo midias as mídias anexadas são removidas
+	if erro = repositorio.VincularMidias(publicacaoId, publicacao.Midias); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
 		return
 	}
 
 	respostas.JSON(w, http.StatusNoContent, nil)
 }
 
-// DeletarPublicacao deleta uma publicacao no banco de dados
-func DeletarPublicacao(w http.ResponseWriter, r *http.Request) {
-	usuarioID, erro := autenticacao.ExtrairUsuarioID(r)
+// EditarPublicacao altera apenas os campos enviados, seguindo o JSON Merge Patch
+func EditarPublicacao(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+	parametros := mux.Vars(r)
+
+	publicacaoId, erro := strconv.ParseUint(parametros["publicacaoId"], 10, 64)
 	if erro != nil {
-		respostas.Erro(w, http.StatusUnauthorized, erro)
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	body, erro := ioutil.ReadAll(r.Body)
+	if erro != nil {
+		respostas.Erro(w, http.StatusUnprocessableEntity, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositoriosDePublicacoes(db)
+	publicacao, erro := repositorio.BuscarPorID(publicacaoId, usuarioID)
+	if erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	if publicacao.AutorID != usuarioID {
+		respostas.Erro(w, http.StatusForbidden, errors.New("Não é possivel atualizar uma publicação que não é sua "))
+		return
+	}
+
+	versao, ok := versaoEsperada(w, r, publicacao.Versao)
+	if !ok {
+		return
+	}
+
+	campos, erro := modelos.AplicarMergePatch(&publicacao, body, "titulo", "conteudo", "midias", "visibilidade")
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	if erro = publicacao.PrepararPatch(campos); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	// As mídias são conferidas antes de gravar, para um patch recusado não alterar nada
+	if campoAlterado(campos, "midias") {
+		if status, erro := validarMidias(db, publicacao.Midias, usuarioID); erro != nil {
+			respostas.Erro(w, status, erro)
+			return
+		}
+	}
+
+	if erro = repositorio.Atualizar(publicacaoId, publicacao, versao); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
 		return
 	}
+
+	if erro = repositorio.VincularTags(publicacaoId, publicacao.Tags); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	publicacao.ID, publicacao.AutorID = publicacaoId, usuarioID
+	if _, erro = vincularMencoes(db, publicacao); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	// As mídias só são trocadas quando o patch traz o campo midias
+	if campoAlterado(campos, "midias") {
+		if erro = repositorio.VincularMidias(publicacaoId, publicacao.Midias); erro != nil {
+			respostas.Erro(w, http.StatusInternalServerError, erro)
+			return
+		}
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// DeletarPublicacao deleta uma publicacao no banco de dados
+func DeletarPublicacao(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
 	parametros := mux.Vars(r)
 
 	publicacaoId, erro := strconv.ParseUint(parametros["publicacaoId"], 10, 64)
@@ -194,9 +324,9 @@ func DeletarPublicacao(w http.ResponseWriter, r *http.Request) {
 	defer db.Close()
 
 	repositorio := repositorio.NovoRepositoriosDePublicacoes(db)
-	publicacaoSalvaNoBanco, erro := repositorio.BuscarPorID(publicacaoId)
+	publicacaoSalvaNoBanco, erro := repositorio.BuscarPorID(publicacaoId, usuarioID)
 	if erro != nil {
-		respostas.Erro(w, http.StatusBadRequest, erro)
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
 		return
 	}
 
@@ -204,8 +334,13 @@ func DeletarPublicacao(w http.ResponseWriter, r *http.Request) {
 		respostas.Erro(w, http.StatusForbidden, errors.New("Não é possivel deletar uma publicação que não é sua "))
 		return
 	}
-	if erro = repositorio.Deletar(publicacaoId); erro != nil {
-		respostas.Erro(w, http.StatusBadRequest, erro)
+
+	versao, ok := versaoEsperada(w, r, publicacaoSalvaNoBanco.Versao)
+	if !ok {
+		return
+	}
+	if erro = repositorio.Deletar(publicacaoId, versao); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
 		return
 	}
 
@@ -232,7 +367,7 @@ func BuscarPublicacaoPorUsuario(w http.ResponseWriter, r *http.Request) {
 
 	repositorio := repositorio.NovoRepositoriosDePublicacoes(db)
 
-	publicacoes, erro := repositorio.BuscarPorUsuario(usuarioId)
+	publicacoes, erro := repositorio.BuscarPorUsuario(usuarioId, autenticacao.UsuarioDaRequisicao(r).ID)
 
 	if erro != nil {
 		respostas.Erro(w, http.StatusInternalServerError, erro)
@@ -243,6 +378,7 @@ func BuscarPublicacaoPorUsuario(w http.ResponseWriter, r *http.Request) {
 
 // CurtirPublicacao adiciona uma curtida na publicacao
 func CurtirPublicacao(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
 	parametros := mux.Vars(r)
 	publicacaoId, erro := strconv.ParseUint(parametros["publicacaoId"], 10, 64)
 	if erro != nil {
@@ -258,16 +394,31 @@ func CurtirPublicacao(w http.ResponseWriter, r *http.Request) {
 	defer db.Close()
 
 	repositorio := repositorio.NovoRepositoriosDePublicacoes(db)
+	publicacao, erro := repositorio.BuscarPorID(publicacaoId, usuarioID)
+	if erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
 	if erro = repositorio.Curtir(publicacaoId); erro != nil {
 		respostas.Erro(w, http.StatusInternalServerError, erro)
 		return
 	}
 
+	notificacoes.Emitir(db, modelos.Notificacao{
+		UsuarioID:    publicacao.AutorID,
+		Tipo:         modelos.NotificacaoCurtida,
+		PublicacaoID: &publicacao.ID,
+		AtorID:       usuarioID,
+	})
+	publicarCurtidas(db, publicacao)
+
 	respostas.JSON(w, http.StatusNoContent, nil)
 }
 
 // CurtirPublicacao remove uma curtida na publicacao
 func DescurtirPublicacao(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
 	parametros := mux.Vars(r)
 	publicacaoId, erro := strconv.ParseUint(parametros["publicacaoId"], 10, 64)
 	if erro != nil {
@@ -283,10 +434,101 @@ func DescurtirPublicacao(w http.ResponseWriter, r *http.Request) {
 	defer db.Close()
 
 	repositorio := repositorio.NovoRepositoriosDePublicacoes(db)
+	publicacao, erro := repositorio.BuscarPorID(publicacaoId, usuarioID)
+	if erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
 	if erro = repositorio.DesCurtir(publicacaoId); erro != nil {
 		respostas.Erro(w, http.StatusInternalServerError, erro)
 		return
 	}
+	publicarCurtidas(db, publicacao)
 
 	respostas.JSON(w, http.StatusNoContent, nil)
 }
+
+// RepostarPublicacao coloca a publicação no feed dos seguidores do usuário logado
+func RepostarPublicacao(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+	parametros := mux.Vars(r)
+	publicacaoId, erro := strconv.ParseUint(parametros["publicacaoId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositoriosDePublicacoes(db)
+	if _, erro = repositorio.BuscarPorID(publicacaoId, usuarioID); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	if erro = repositorio.Repostar(publicacaoId, usuarioID); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// DesfazerRepostagem tira a repostagem do usuário logado
+func DesfazerRepostagem(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+	parametros := mux.Vars(r)
+	publicacaoId, erro := strconv.ParseUint(parametros["publicacaoId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositoriosDePublicacoes(db)
+	if erro = repositorio.DesfazerRepostagem(publicacaoId, usuarioID); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// validarMidias garante que as mídias anexadas a uma publicação foram enviadas pelo autor
+func validarMidias(db *sql.DB, midias []uint64, usuarioID uint64) (int, error) {
+	if len(midias) == 0 {
+		return http.StatusOK, nil
+	}
+
+	pertencem, erro := repositorio.NovoRepositorioDeMidias(db).PertencemAoUsuario(midias, usuarioID)
+	if erro != nil {
+		return http.StatusInternalServerError, erro
+	}
+
+	if !pertencem {
+		return http.StatusBadRequest, errors.New("Só é possível anexar mídias enviadas por você")
+	}
+	return http.StatusOK, nil
+}
+
+// campoAlterado informa se o campo veio no merge patch
+func campoAlterado(campos []string, campo string) bool {
+	for _, alterado := range campos {
+		if alterado == campo {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"api/src/autenticacao"
+	"api/src/banco"
+	"api/src/email"
+	"api/src/modelos"
+	"api/src/repositorio"
+	"api/src/respostas"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// iniciarSessao registra o login do usuário no dispositivo da requisição e retorna o token da sessão.
+// O usuário é avisado por email quando o login vem de um dispositivo que ele nunca usou
+func iniciarSessao(db *sql.DB, r *http.Request, usuario modelos.Usuario) (string, error) {
+	sessaoID, erro := autenticacao.NovoTokenID()
+	if erro != nil {
+		return "", erro
+	}
+
+	sessao := modelos.Sessao{
+		ID:        sessaoID,
+		UsuarioID: usuario.ID,
+		UserAgent: limitar(r.UserAgent(), 255),
+		IP:        enderecoIP(r),
+		ExpiraEm:  time.Now().Add(autenticacao.DuracaoToken),
+	}
+
+	repositorio := repositorio.NovoRepositorioDeSessoes(db)
+
+	primeiroLogin, erro := repositorio.PrimeiroLogin(usuario.ID)
+	if erro != nil {
+		return "", erro
+	}
+
+	dispositivoConhecido, erro := repositorio.DispositivoConhecido(usuario.ID, sessao.UserAgent)
+	if erro != nil {
+		return "", erro
+	}
+
+	if erro = repositorio.Criar(sessao); erro != nil {
+		return "", erro
+	}
+
+	token, erro := autenticacao.CriarToken(usuario.ID, usuario.Nick, sessao.ID)
+	if erro != nil {
+		return "", erro
+	}
+
+	if !primeiroLogin && !dispositivoConhecido && usuario.Email != "" {
+		email.EnviarEmSegundoPlano(email.Mensagem{
+			Para:    usuario.Email,
+			Assunto: "Novo login na sua conta do DevBook",
+			Corpo: fmt.Sprintf(
+				"Olá %s,\n\nDetectamos um login em um novo dispositivo.\n\nDispositivo: %s\nIP: %s\n\n"+
+					"Se não foi você, encerre a sessão e altere a sua senha.",
+				usuario.Nick, sessao.UserAgent, sessao.IP,
+			),
+		})
+	}
+
+	return token, nil
+}
+
+// BuscarSessoes traz as sessões ativas do usuário logado
+func BuscarSessoes(w http.ResponseWriter, r *http.Request) {
+	usuario := autenticacao.UsuarioDaRequisicao(r)
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeSessoes(db)
+
+	sessoes, erro := repositorio.BuscarAtivas(usuario.ID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	for i := range sessoes {
+		sessoes[i].Atual = sessoes[i].ID == usuario.TokenID
+	}
+
+	respostas.JSON(w, http.StatusOK, sessoes)
+}
+
+// RevogarSessao encerra uma sessão do usuário logado
+func RevogarSessao(w http.ResponseWriter, r *http.Request) {
+	usuario := autenticacao.UsuarioDaRequisicao(r)
+	parametros := mux.Vars(r)
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeSessoes(db)
+
+	revogada, erro := repositorio.Revogar(parametros["sessaoId"], usuario.ID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	if !revogada {
+		respostas.Erro(w, http.StatusNotFound, errors.New("Sessão não encontrada"))
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// RevogarOutrasSessoes encerra todas as sessões do usuário logado exceto a atual
+func RevogarOutrasSessoes(w http.ResponseWriter, r *http.Request) {
+	usuario := autenticacao.UsuarioDaRequisicao(r)
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeSessoes(db)
+
+	if erro = repositorio.RevogarOutras(usuario.ID, usuario.TokenID); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+func enderecoIP(r *http.Request) string {
+	ip, _, erro := net.SplitHostPort(r.RemoteAddr)
+	if erro != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// limitar corta o texto em até tamanho bytes sem partir um caractere UTF-8 no meio, e
+// descarta bytes inválidos, que o banco recusaria
+func limitar(texto string, tamanho int) string {
+	texto = strings.ToValidUTF8(texto, "")
+	if len(texto) <= tamanho {
+		return texto
+	}
+
+	for tamanho > 0 && !utf8.RuneStart(texto[tamanho]) {
+		tamanho--
+	}
+	return texto[:tamanho]
+}
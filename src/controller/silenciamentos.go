@@ -0,0 +1,200 @@
+package controller
+
+import (
+	"api/src/autenticacao"
+	"api/src/banco"
+	"api/src/modelos"
+	"api/src/repositorio"
+	"api/src/respostas"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// SilenciarUsuario tira um usuário do feed do usuário logado, sem avisar o silenciado.
+// O corpo é opcional e pode trazer a expiração em expiraEm
+func SilenciarUsuario(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	silenciadoID, erro := strconv.ParseUint(mux.Vars(r)["usuarioId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	if silenciadoID == usuarioID {
+		respostas.Erro(w, http.StatusForbidden, errors.New("Não é possivel silenciar você mesmo"))
+		return
+	}
+
+	body, erro := ioutil.ReadAll(r.Body)
+	if erro != nil {
+		respostas.Erro(w, http.StatusUnprocessableEntity, erro)
+		return
+	}
+
+	var silenciamento modelos.Silenciamento
+	if len(body) > 0 {
+		if erro = json.Unmarshal(body, &silenciamento); erro != nil {
+			respostas.Erro(w, http.StatusBadRequest, erro)
+			return
+		}
+	}
+	silenciamento.UsuarioID = silenciadoID
+
+	if erro = silenciamento.Preparar(false); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeSilenciamentos(db)
+	if erro = repositorio.SilenciarUsuario(usuarioID, silenciamento); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// DessilenciarUsuario devolve um usuário silenciado ao feed
+func DessilenciarUsuario(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	silenciadoID, erro := strconv.ParseUint(mux.Vars(r)["usuarioId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeSilenciamentos(db)
+	if erro = repositorio.DessilenciarUsuario(usuarioID, silenciadoID); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// BuscarUsuariosSilenciados lista os usuários silenciados pelo usuário logado
+func BuscarUsuariosSilenciados(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeSilenciamentos(db)
+	silenciamentos, erro := repositorio.BuscarUsuarios(usuarioID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusOK, silenciamentos)
+}
+
+// SilenciarTermo tira do feed do usuário logado as publicações com a palavra ou frase
+func SilenciarTermo(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	body, erro := ioutil.ReadAll(r.Body)
+	if erro != nil {
+		respostas.Erro(w, http.StatusUnprocessableEntity, erro)
+		return
+	}
+
+	var silenciamento modelos.Silenciamento
+	if erro = json.Unmarshal(body, &silenciamento); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	if erro = silenciamento.Preparar(true); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeSilenciamentos(db)
+	silenciamento.ID, erro = repositorio.SilenciarTermo(usuarioID, silenciamento)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusCreated, silenciamento)
+}
+
+// DessilenciarTermo remove uma palavra ou frase silenciada
+func DessilenciarTermo(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	termoID, erro := strconv.ParseUint(mux.Vars(r)["termoId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeSilenciamentos(db)
+	if erro = repositorio.DessilenciarTermo(usuarioID, termoID); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// BuscarTermosSilenciados lista as palavras e frases silenciadas pelo usuário logado
+func BuscarTermosSilenciados(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeSilenciamentos(db)
+	silenciamentos, erro := repositorio.BuscarTermos(usuarioID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusOK, silenciamentos)
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"api/src/autenticacao"
+	"api/src/banco"
+	"api/src/repositorio"
+	"api/src/respostas"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// BuscarSolicitacoesRecebidas lista quem pediu para seguir o usuário logado
+func BuscarSolicitacoesRecebidas(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
+	usuarios, erro := repositorio.BuscarSolicitacoesRecebidas(usuarioID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusOK, usuarios)
+}
+
+// BuscarSolicitacoesEnviadas lista as contas privadas que o usuário logado pediu para seguir
+func BuscarSolicitacoesEnviadas(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
+	usuarios, erro := repositorio.BuscarSolicitacoesEnviadas(usuarioID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusOK, usuarios)
+}
+
+// AprovarSolicitacao aceita o pedido de um usuário para seguir o usuário logado
+func AprovarSolicitacao(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	seguidorID, erro := strconv.ParseUint(mux.Vars(r)["seguidorId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
+	if erro = repositorio.AprovarSolicitacao(usuarioID, seguidorID); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// RecusarSolicitacao descarta o pedido de um usuário para seguir o usuário logado
+func RecusarSolicitacao(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	seguidorID, erro := strconv.ParseUint(mux.Vars(r)["seguidorId"], 10, 64)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
+	if erro = repositorio.RecusarSolicitacao(usuarioID, seguidorID); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"api/src/autenticacao"
+	"api/src/banco"
+	"api/src/modelos"
+	"api/src/repositorio"
+	"api/src/respostas"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// limiteSugestoesDeTags é a quantidade de tags retornadas no autocompletar
+const limiteSugestoesDeTags = 10
+
+// BuscarTags sugere tags que começam com o prefixo informado na query prefixo
+func BuscarTags(w http.ResponseWriter, r *http.Request) {
+	prefixo := modelos.NormalizarTag(r.URL.Query().Get("prefixo"))
+	if prefixo == "" {
+		respostas.Erro(w, http.StatusBadRequest, errors.New("Informe um prefixo válido para buscar tags"))
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeTags(db)
+	tags, erro := repositorio.BuscarPorPrefixo(prefixo, limiteSugestoesDeTags)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusOK, tags)
+}
+
+// BuscarPublicacoesPorTag traz as publicações com a tag
+func BuscarPublicacoesPorTag(w http.ResponseWriter, r *http.Request) {
+	tag, ok := tagDaRota(w, r)
+	if !ok {
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositoriosDePublicacoes(db)
+	publicacoes, erro := repositorio.BuscarPorTag(tag, autenticacao.UsuarioDaRequisicao(r).ID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusOK, publicacoes)
+}
+
+// SeguirTag coloca as publicações com a tag no feed do usuário logado
+func SeguirTag(w http.ResponseWriter, r *http.Request) {
+	tag, ok := tagDaRota(w, r)
+	if !ok {
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeTags(db)
+	if erro = repositorio.Seguir(autenticacao.UsuarioDaRequisicao(r).ID, tag); erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// PararDeSeguirTag tira as publicações com a tag do feed do usuário logado
+func PararDeSeguirTag(w http.ResponseWriter, r *http.Request) {
+	tag, ok := tagDaRota(w, r)
+	if !ok {
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeTags(db)
+	if erro = repositorio.PararDeSeguir(autenticacao.UsuarioDaRequisicao(r).ID, tag); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// BuscarTagsSeguidas lista as tags seguidas pelo usuário logado
+func BuscarTagsSeguidas(w http.ResponseWriter, r *http.Request) {
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeTags(db)
+	tags, erro := repositorio.BuscarSeguidas(autenticacao.UsuarioDaRequisicao(r).ID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	respostas.JSON(w, http.StatusOK, tags)
+}
+
+// tagDaRota lê e normaliza a tag da URL, respondendo 400 quando ela é inválida
+func tagDaRota(w http.ResponseWriter, r *http.Request) (string, bool) {
+	tag := modelos.NormalizarTag(mux.Vars(r)["tag"])
+	if tag == "" {
+		respostas.Erro(w, http.StatusBadRequest, errors.New("Tag inválida"))
+		return "", false
+	}
+	return tag, true
+}
@@ -4,6 +4,7 @@ import (
 	"api/src/autenticacao"
 	"api/src/banco"
 	"api/src/modelos"
+	"api/src/notificacoes"
 	"api/src/repositorio"
 	"api/src/respostas"
 	"api/src/seguranca"
@@ -65,7 +66,7 @@ func BuscarUsuarios(w http.ResponseWriter, r *http.Request) {
 
 	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
 
-	usuarios, erro := repositorio.Buscar(nomeOuNick)
+	usuarios, erro := repositorio.Buscar(nomeOuNick, autenticacao.UsuarioDaRequisicao(r).ID)
 
 	if erro != nil {
 		respostas.Erro(w, http.StatusInternalServerError, erro)
@@ -93,6 +94,10 @@ func BuscarUsuario(w http.ResponseWriter, r *http.Request) {
 	}
 	defer db.Close()
 
+	if perfilBloqueado(w, db, autenticacao.UsuarioDaRequisicao(r).ID, usuarioID) {
+		return
+	}
+
 	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
 
 	usuario, erro := repositorio.BuscarPorID(usuarioID)
@@ -102,7 +107,12 @@ func BuscarUsuario(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respostas.JSON(w, http.StatusOK, usuario)
+	if usuario.ID == 0 {
+		respostas.Erro(w, http.StatusNotFound, errors.New("Usuário não encontrado"))
+		return
+	}
+
+	responderComETag(w, r, usuario.Versao, usuario)
 }
 
 // AtualizarUsuario atualiza dados no banco
@@ -116,12 +126,7 @@ func AtualizarUsuario(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	usuarioIDNoToken, erro := autenticacao.ExtrairUsuarioID(r)
-
-	if erro != nil {
-		respostas.Erro(w, http.StatusUnauthorized, erro)
-		return
-	}
+	usuarioIDNoToken := autenticacao.UsuarioDaRequisicao(r).ID
 
 	if usuarioID != usuarioIDNoToken {
 		respostas.Erro(w, http.StatusForbidden, errors.New("Não tem autorização de alterar usuário que não é seu"))
@@ -140,6 +145,21 @@ func AtualizarUsuario(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// O PUT substitui o usuário inteiro, e um privada ausente tornaria a conta pública e
+	// aprovaria todas as solicitações pendentes sem volta. Por isso o campo é obrigatório
+	var campos map[string]json.RawMessage
+	if erro = json.Unmarshal(body, &campos); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	if privada, informada := campos["privada"]; !informada || string(privada) == "null" {
+		erros := modelos.ErroDeValidacao{}
+		erros.Adicionar("privada", "Informe se a conta é privada; para alterar só alguns campos use PATCH")
+		respostas.Erro(w, http.StatusBadRequest, erros.Erro())
+		return
+	}
+
 	if erro = usuario.Preparar("edicao"); erro != nil {
 		respostas.Erro(w, http.StatusBadRequest, erro)
 		return
@@ -148,21 +168,113 @@ func AtualizarUsuario(w http.ResponseWriter, r *http.Request) {
 	db, erro := banco.Conectar()
 	if erro != nil {
 		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
 	}
 	defer db.Close()
 
-	if erro = usuario.Preparar("edicao"); erro != nil {
+	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
+	usuarioSalvoNoBanco, erro := repositorio.BuscarPorID(usuarioID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	if usuarioSalvoNoBanco.ID == 0 {
+		respostas.Erro(w, http.StatusNotFound, errors.New("Usuário não encontrado"))
+		return
+	}
+
+	versao, ok := versaoEsperada(w, r, usuarioSalvoNoBanco.Versao)
+	if !ok {
+		return
+	}
+
+	if erro = repositorio.Atualizar(usuarioID, usuario, versao); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	if usuarioSalvoNoBanco.Privada && !usuario.Privada {
+		if erro = repositorio.AprovarSolicitacoes(usuarioID); erro != nil {
+			respostas.Erro(w, http.StatusInternalServerError, erro)
+			return
+		}
+	}
+
+	respostas.JSON(w, http.StatusNoContent, nil)
+}
+
+// EditarUsuario altera apenas os campos enviados, seguindo o JSON Merge Patch
+func EditarUsuario(w http.ResponseWriter, r *http.Request) {
+	parametro := mux.Vars(r)
+
+	usuarioID, erro := strconv.ParseUint(parametro["usuarioId"], 10, 64)
+	if erro != nil {
 		respostas.Erro(w, http.StatusBadRequest, erro)
 		return
 	}
 
-	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
+	if usuarioID != autenticacao.UsuarioDaRequisicao(r).ID {
+		respostas.Erro(w, http.StatusForbidden, errors.New("Não tem autorização de alterar usuário que não é seu"))
+		return
+	}
+
+	body, erro := ioutil.ReadAll(r.Body)
+	if erro != nil {
+		respostas.Erro(w, http.StatusUnprocessableEntity, erro)
+		return
+	}
+
+	db, erro := banco.Conectar()
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+	defer db.Close()
 
-	if erro = repositorio.Atualizar(usuarioID, usuario); erro != nil {
+	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
+	usuario, erro := repositorio.BuscarPorID(usuarioID)
+	if erro != nil {
 		respostas.Erro(w, http.StatusInternalServerError, erro)
 		return
 	}
 
+	if usuario.ID == 0 {
+		respostas.Erro(w, http.StatusNotFound, errors.New("Usuário não encontrado"))
+		return
+	}
+
+	versao, ok := versaoEsperada(w, r, usuario.Versao)
+	if !ok {
+		return
+	}
+
+	eraPrivada := usuario.Privada
+	campos, erro := modelos.AplicarMergePatch(&usuario, body,
+		"nome", "nick", "email", "bio", "avatarUrl", "capaUrl", "localizacao", "website", "dataNascimento", "privada",
+	)
+	if erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	if erro = usuario.PrepararPatch(campos); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
+	if erro = repositorio.Atualizar(usuarioID, usuario, versao); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	if eraPrivada && !usuario.Privada {
+		if erro = repositorio.AprovarSolicitacoes(usuarioID); erro != nil {
+			respostas.Erro(w, http.StatusInternalServerError, erro)
+			return
+		}
+	}
+
 	respostas.JSON(w, http.StatusNoContent, nil)
 }
 
@@ -176,12 +288,7 @@ func DeletarUsuario(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	usuarioIDNoToken, erro := autenticacao.ExtrairUsuarioID(r)
-
-	if erro != nil {
-		respostas.Erro(w, http.StatusUnauthorized, erro)
-		return
-	}
+	usuarioIDNoToken := autenticacao.UsuarioDaRequisicao(r).ID
 
 	if usuarioId != usuarioIDNoToken {
 		respostas.Erro(w, http.StatusForbidden, errors.New("Não é possivel deletar outro usuário se não o seu"))
@@ -196,23 +303,33 @@ func DeletarUsuario(w http.ResponseWriter, r *http.Request) {
 	defer db.Close()
 
 	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
-
-	if erro = repositorio.Deletar(usuarioId); erro != nil {
+	usuario, erro := repositorio.BuscarPorID(usuarioId)
+	if erro != nil {
 		respostas.Erro(w, http.StatusInternalServerError, erro)
 		return
 	}
 
+	if usuario.ID == 0 {
+		respostas.Erro(w, http.StatusNotFound, errors.New("Usuário não encontrado"))
+		return
+	}
+
+	versao, ok := versaoEsperada(w, r, usuario.Versao)
+	if !ok {
+		return
+	}
+
+	if erro = repositorio.Deletar(usuarioId, versao); erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
 	respostas.JSON(w, http.StatusNoContent, nil)
 }
 
 // SeguirUsuario permite que um usuário siga outro
 func SeguirUsuario(w http.ResponseWriter, r *http.Request) {
-	seguidorID, erro := autenticacao.ExtrairUsuarioID(r)
-
-	if erro != nil {
-		respostas.Erro(w, http.StatusUnauthorized, erro)
-		return
-	}
+	seguidorID := autenticacao.UsuarioDaRequisicao(r).ID
 
 	parametros := mux.Vars(r)
 
@@ -238,21 +355,26 @@ func SeguirUsuario(w http.ResponseWriter, r *http.Request) {
 
 	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
 
-	if erro = repositorio.Seguir(usuarioID, seguidorID); erro != nil {
-		respostas.Erro(w, http.StatusInternalServerError, erro)
+	pendente, erro := repositorio.Seguir(usuarioID, seguidorID)
+	if erro != nil {
+		respostas.Erro(w, statusDoRepositorio(erro), erro)
+		return
+	}
+
+	// Contas privadas precisam aprovar a solicitação antes do seguidor ver as publicações
+	if pendente {
+		notificacoes.Emitir(db, modelos.Notificacao{UsuarioID: usuarioID, Tipo: modelos.NotificacaoSolicitacao, AtorID: seguidorID})
+		respostas.JSON(w, http.StatusAccepted, nil)
 		return
 	}
+
+	notificacoes.Emitir(db, modelos.Notificacao{UsuarioID: usuarioID, Tipo: modelos.NotificacaoSeguidor, AtorID: seguidorID})
 	respostas.JSON(w, http.StatusNoContent, nil)
 }
 
 // PararDeSeguirUsuario permite que um usuário siga outro
 func PararDeSeguirUsuario(w http.ResponseWriter, r *http.Request) {
-	seguidorID, erro := autenticacao.ExtrairUsuarioID(r)
-
-	if erro != nil {
-		respostas.Erro(w, http.StatusUnauthorized, erro)
-		return
-	}
+	seguidorID := autenticacao.UsuarioDaRequisicao(r).ID
 
 	parametros := mux.Vars(r)
 
@@ -303,6 +425,10 @@ func BuscarSeguidores(w http.ResponseWriter, r *http.Request) {
 	}
 	defer db.Close()
 
+	if perfilBloqueado(w, db, autenticacao.UsuarioDaRequisicao(r).ID, usuarioID) {
+		return
+	}
+
 	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
 
 	seguidores, erro := repositorio.BuscarSeguidores(usuarioID)
@@ -332,6 +458,10 @@ func BuscarSeguindo(w http.ResponseWriter, r *http.Request) {
 	}
 	defer db.Close()
 
+	if perfilBloqueado(w, db, autenticacao.UsuarioDaRequisicao(r).ID, usuarioID) {
+		return
+	}
+
 	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
 
 	usuarios, erro := repositorio.BuscarSeguindo(usuarioID)
@@ -346,11 +476,7 @@ func BuscarSeguindo(w http.ResponseWriter, r *http.Request) {
 
 // AtualizarSenha alterar senha de um usuário
 func AtualizarSenha(w http.ResponseWriter, r *http.Request) {
-	usuarioIDNoToken, erro := autenticacao.ExtrairUsuarioID(r)
-	if erro != nil {
-		respostas.Erro(w, http.StatusUnauthorized, erro)
-		return
-	}
+	usuarioIDNoToken := autenticacao.UsuarioDaRequisicao(r).ID
 
 	parametros := mux.Vars(r)
 
@@ -383,6 +509,17 @@ func AtualizarSenha(w http.ResponseWriter, r *http.Request) {
 
 	repositorio := repositorio.NovoRepositoriosDeUsuarios(db)
 
+	usuarioSalvoNoBanco, erro := repositorio.BuscarPorID(usuarioID)
+	if erro != nil {
+		respostas.Erro(w, http.StatusInternalServerError, erro)
+		return
+	}
+
+	if erro = senha.Validar(usuarioSalvoNoBanco.Nick, usuarioSalvoNoBanco.Email); erro != nil {
+		respostas.Erro(w, http.StatusBadRequest, erro)
+		return
+	}
+
 	senhaSalvaNoBanco, erro := repositorio.BuscarSenha(usuarioID)
 	if erro != nil {
 		respostas.Erro(w, http.StatusInternalServerError, erro)
@@ -0,0 +1,301 @@
+package controller
+
+import (
+	"api/src/autenticacao"
+	"api/src/banco"
+	"api/src/config"
+	"api/src/eventos"
+	"api/src/repositorio"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// esperaPorPong é quanto tempo a conexão fica aberta sem notícias do cliente
+	esperaPorPong = 60 * time.Second
+	// intervaloDoPing precisa ser menor que esperaPorPong para o pong chegar a tempo
+	intervaloDoPing = 25 * time.Second
+	// esperaPorEscrita é o limite para entregar uma mensagem ao cliente
+	esperaPorEscrita = 10 * time.Second
+	// tamanhoMaximoDaMensagem limita as mensagens do cliente, que só assinam e cancelam tópicos
+	tamanhoMaximoDaMensagem = 1024
+)
+
+var atualizador = websocket.Upgrader{CheckOrigin: origemPermitida}
+
+// origemPermitida aceita clientes sem Origin (aplicativos), o front-end configurado e o próprio
+// host da API. Sem isso um site qualquer abriria a conexão com o cookie do usuário
+func origemPermitida(r *http.Request) bool {
+	origem := r.Header.Get("Origin")
+	if origem == "" || (config.URLApp != "" && strings.TrimSuffix(config.URLApp, "/") == origem) {
+		return true
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(origem, "https://"), "http://") == r.Host
+}
+
+// mensagemDoCliente é o que o cliente envia para assinar ou cancelar um tópico
+type mensagemDoCliente struct {
+	Acao   string `json:"acao"`
+	Topico string `json:"topico"`
+}
+
+// mensagemDoServidor é um evento de um tópico assinado ou a resposta a uma mensagem do cliente
+type mensagemDoServidor struct {
+	Topico string      `json:"topico,omitempty"`
+	ID     uint64      `json:"id,omitempty"`
+	Tipo   string      `json:"tipo"`
+	Dados  interface{} `json:"dados,omitempty"`
+	Em     *time.Time  `json:"em,omitempty"`
+	Erro   string      `json:"erro,omitempty"`
+}
+
+// conexaoWebSocket guarda as assinaturas de um cliente. Só a goroutine de escrita escreve na
+// conexão; as assinaturas são protegidas pelo mutex, já que uma assinatura de publicação
+// também é encerrada quando o usuário perde o acesso a ela
+type conexaoWebSocket struct {
+	ws          *websocket.Conn
+	usuarioID   uint64
+	saida       chan mensagemDoServidor
+	fim         chan struct{}
+	mutex       sync.Mutex
+	assinaturas map[string]*eventos.Assinatura
+}
+
+// ConectarWebSocket abre um canal bidirecional em que o cliente assina tópicos e recebe os
+// eventos deles: feed, notificacoes, curtidas (das próprias publicações), mensagens e
+// publicacao:{id}
+func ConectarWebSocket(w http.ResponseWriter, r *http.Request) {
+	usuarioID := autenticacao.UsuarioDaRequisicao(r).ID
+
+	ws, erro := atualizador.Upgrade(w, r, nil)
+	if erro != nil {
+		// O Upgrade já respondeu ao cliente com o erro
+		return
+	}
+
+	conexao := &conexaoWebSocket{
+		ws:          ws,
+		usuarioID:   usuarioID,
+		saida:       make(chan mensagemDoServidor, 16),
+		fim:         make(chan struct{}),
+		assinaturas: map[string]*eventos.Assinatura{},
+	}
+
+	go conexao.escrever()
+	conexao.ler()
+}
+
+// ler trata as mensagens do cliente até a conexão cair, e então encerra as assinaturas
+func (c *conexaoWebSocket) ler() {
+	defer func() {
+		close(c.fim)
+		c.mutex.Lock()
+		for _, assinatura := range c.assinaturas {
+			assinatura.Cancelar()
+		}
+		c.mutex.Unlock()
+		c.ws.Close()
+	}()
+
+	c.ws.SetReadLimit(tamanhoMaximoDaMensagem)
+	c.ws.SetReadDeadline(time.Now().Add(esperaPorPong))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(esperaPorPong))
+	})
+
+	for {
+		_, conteudo, erro := c.ws.ReadMessage()
+		if erro != nil {
+			var fechamento *websocket.CloseError
+			if !errors.As(erro, &fechamento) && !errors.Is(erro, websocket.ErrReadLimit) {
+				log.Printf("Conexão WebSocket do usuário %d encerrada: %v", c.usuarioID, erro)
+			}
+			return
+		}
+
+		var mensagem mensagemDoCliente
+		if erro = json.Unmarshal(conteudo, &mensagem); erro != nil {
+			c.enviar(mensagemDoServidor{Tipo: "erro", Erro: "Mensagem inválida"})
+			continue
+		}
+
+		switch mensagem.Acao {
+		case "assinar":
+			c.assinar(mensagem.Topico)
+		case "cancelar":
+			c.cancelar(mensagem.Topico)
+		default:
+			c.enviar(mensagemDoServidor{Topico: mensagem.Topico, Tipo: "erro", Erro: "A ação deve ser assinar ou cancelar"})
+		}
+	}
+}
+
+// assinar passa a repassar ao cliente os eventos do tópico
+func (c *conexaoWebSocket) assinar(topico string) {
+	c.mutex.Lock()
+	_, assinado := c.assinaturas[topico]
+	c.mutex.Unlock()
+	if assinado {
+		c.enviar(mensagemDoServidor{Topico: topico, Tipo: "assinado"})
+		return
+	}
+
+	interno, erro := c.topicoInterno(topico)
+	if erro != nil {
+		c.enviar(mensagemDoServidor{Topico: topico, Tipo: "erro", Erro: erro.Error()})
+		return
+	}
+
+	assinatura, _, _ := eventos.Assinar(0, interno)
+	c.mutex.Lock()
+	c.assinaturas[topico] = assinatura
+	c.mutex.Unlock()
+
+	publicacaoID, daPublicacao := publicacaoDoTopico(topico)
+
+	go func() {
+		for evento := range assinatura.Eventos {
+			// O acesso à publicação é conferido de novo a cada evento, porque o autor pode ter
+			// mudado a visibilidade ou bloqueado o usuário depois da assinatura
+			if daPublicacao {
+				erro := c.podeAcompanhar(publicacaoID)
+				if errors.Is(erro, repositorio.ErrNaoEncontrado) {
+					c.revogar(topico, assinatura)
+					return
+				}
+				if erro != nil {
+					log.Printf("Erro ao conferir a publicação %d: %v", publicacaoID, erro)
+					continue
+				}
+			}
+
+			em := evento.Em
+			select {
+			case c.saida <- mensagemDoServidor{Topico: topico, ID: evento.ID, Tipo: evento.Tipo, Dados: evento.Dados, Em: &em}:
+			case <-c.fim:
+				return
+			}
+		}
+	}()
+
+	c.enviar(mensagemDoServidor{Topico: topico, Tipo: "assinado"})
+}
+
+// cancelar para de repassar os eventos do tópico
+func (c *conexaoWebSocket) cancelar(topico string) {
+	c.mutex.Lock()
+	if assinatura, assinado := c.assinaturas[topico]; assinado {
+		assinatura.Cancelar()
+		delete(c.assinaturas, topico)
+	}
+	c.mutex.Unlock()
+	c.enviar(mensagemDoServidor{Topico: topico, Tipo: "cancelado"})
+}
+
+// revogar encerra a assinatura de uma publicação que o usuário deixou de poder ver. Se o
+// cliente já trocou a assinatura do tópico, a nova é mantida
+func (c *conexaoWebSocket) revogar(topico string, assinatura *eventos.Assinatura) {
+	c.mutex.Lock()
+	if c.assinaturas[topico] == assinatura {
+		delete(c.assinaturas, topico)
+	}
+	c.mutex.Unlock()
+
+	assinatura.Cancelar()
+	c.enviar(mensagemDoServidor{Topico: topico, Tipo: "cancelado", Erro: repositorio.ErrNaoEncontrado.Error()})
+}
+
+// topicoInterno traduz o tópico pedido pelo cliente no tópico do broker, conferindo se o
+// usuário pode acompanhá-lo
+func (c *conexaoWebSocket) topicoInterno(topico string) (string, error) {
+	switch topico {
+	case "feed":
+		return eventos.TopicoFeed(c.usuarioID), nil
+	case "notificacoes":
+		return eventos.TopicoNotificacoes(c.usuarioID), nil
+	case "curtidas":
+		return eventos.TopicoCurtidas(c.usuarioID), nil
+	case "mensagens":
+		return eventos.TopicoMensagens(c.usuarioID), nil
+	}
+
+	publicacaoID, daPublicacao := publicacaoDoTopico(topico)
+	if !daPublicacao {
+		return "", errors.New("Tópico desconhecido")
+	}
+
+	if erro := c.podeAcompanhar(publicacaoID); erro != nil {
+		if errors.Is(erro, repositorio.ErrNaoEncontrado) {
+			return "", erro
+		}
+		log.Printf("Erro ao conferir a publicação %d: %v", publicacaoID, erro)
+		return "", errors.New("Não foi possível assinar o tópico")
+	}
+	return eventos.TopicoPublicacao(publicacaoID), nil
+}
+
+// publicacaoDoTopico extrai o id de um tópico publicacao:{id}
+func publicacaoDoTopico(topico string) (uint64, bool) {
+	if !strings.HasPrefix(topico, "publicacao:") {
+		return 0, false
+	}
+
+	publicacaoID, erro := strconv.ParseUint(strings.TrimPrefix(topico, "publicacao:"), 10, 64)
+	return publicacaoID, erro == nil
+}
+
+// podeAcompanhar retorna ErrNaoEncontrado quando o usuário não pode ver a publicação
+func (c *conexaoWebSocket) podeAcompanhar(publicacaoID uint64) error {
+	db, erro := banco.Conectar()
+	if erro != nil {
+		return erro
+	}
+	defer db.Close()
+
+	_, erro = repositorio.NovoRepositoriosDePublicacoes(db).BuscarPorID(publicacaoID, c.usuarioID)
+	return erro
+}
+
+// enviar entrega uma mensagem ao cliente, a não ser que a conexão já tenha caído
+func (c *conexaoWebSocket) enviar(mensagem mensagemDoServidor) {
+	select {
+	case c.saida <- mensagem:
+	case <-c.fim:
+	}
+}
+
+// escrever é a única goroutine que escreve na conexão: as mensagens e os pings
+func (c *conexaoWebSocket) escrever() {
+	ping := time.NewTicker(intervaloDoPing)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-c.fim:
+			return
+
+		case mensagem := <-c.saida:
+			c.ws.SetWriteDeadline(time.Now().Add(esperaPorEscrita))
+			if erro := c.ws.WriteJSON(mensagem); erro != nil {
+				// Fechar a conexão faz a leitura falhar e encerrar o resto
+				c.ws.Close()
+				return
+			}
+
+		case <-ping.C:
+			c.ws.SetWriteDeadline(time.Now().Add(esperaPorEscrita))
+			if erro := c.ws.WriteMessage(websocket.PingMessage, nil); erro != nil {
+				c.ws.Close()
+				return
+			}
+		}
+	}
+}
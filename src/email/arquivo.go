@@ -0,0 +1,30 @@
+package email
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Arquivo grava cada email como um arquivo .eml no diretório informado
+type Arquivo struct {
+	Diretorio string
+}
+
+// Enviar grava a mensagem em um arquivo novo
+func (a Arquivo) Enviar(mensagem Mensagem) error {
+	if erro := os.MkdirAll(a.Diretorio, 0o755); erro != nil {
+		return erro
+	}
+
+	agora := time.Now()
+	destinatario := strings.NewReplacer("@", "_", "/", "_", "\\", "_").Replace(mensagem.Para)
+	nome := fmt.Sprintf("%s-%s.eml", agora.Format("20060102T150405.000000000"), destinatario)
+
+	conteudo := fmt.Sprintf("To: %s\r\nSubject: %s\r\nDate: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		mensagem.Para, mensagem.Assunto, agora.Format(time.RFC1123Z), mensagem.Corpo)
+
+	return os.WriteFile(filepath.Join(a.Diretorio, nome), []byte(conteudo), 0o644)
+}
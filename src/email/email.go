@@ -0,0 +1,58 @@
+package email
+
+import (
+	"api/src/config"
+	"log"
+)
+
+// Mensagem representa um email enviado pela API
+type Mensagem struct {
+	Para    string
+	Assunto string
+	Corpo   string
+}
+
+// Enviador é implementado pelos meios de entrega de email
+type Enviador interface {
+	Enviar(mensagem Mensagem) error
+}
+
+// Log escreve os emails no log em vez de entregá-los, útil em desenvolvimento
+type Log struct{}
+
+// Enviar escreve a mensagem no log
+func (Log) Enviar(mensagem Mensagem) error {
+	log.Printf("\nEmail para %s: %s\n%s", mensagem.Para, mensagem.Assunto, mensagem.Corpo)
+	return nil
+}
+
+var enviador Enviador = Log{}
+
+// Configurar troca o enviador usado pela API
+func Configurar(e Enviador) {
+	enviador = e
+}
+
+// Carregar escolhe o enviador conforme a configuração
+func Carregar() {
+	switch config.EmailEnviador {
+	case "arquivo":
+		Configurar(Arquivo{Diretorio: config.EmailDiretorio})
+	default:
+		Configurar(Log{})
+	}
+}
+
+// Enviar entrega a mensagem pelo enviador configurado
+func Enviar(mensagem Mensagem) error {
+	return enviador.Enviar(mensagem)
+}
+
+// EnviarEmSegundoPlano entrega a mensagem sem bloquear a requisição, registrando falhas no log
+func EnviarEmSegundoPlano(mensagem Mensagem) {
+	go func() {
+		if erro := Enviar(mensagem); erro != nil {
+			log.Printf("\nFalha ao enviar email para %s: %v", mensagem.Para, erro)
+		}
+	}()
+}
@@ -0,0 +1,97 @@
+package eventos
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Tipos de evento enviados aos clientes conectados
+const (
+	EventoPublicacao      = "publicacao"
+	EventoNotificacao     = "notificacao"
+	EventoCurtidas        = "curtidas"
+	EventoMensagem        = "mensagem"
+	EventoMensagemApagada = "mensagem_apagada"
+	EventoLeitura         = "leitura"
+	EventoRessincronia    = "ressincronizar"
+)
+
+// Evento é uma mensagem entregue em tempo real. O ID cresce a cada evento publicado e é
+// usado pelo cliente para retomar a conexão de onde parou
+type Evento struct {
+	ID    uint64      `json:"id"`
+	Tipo  string      `json:"tipo"`
+	Dados interface{} `json:"dados"`
+	Em    time.Time   `json:"em"`
+}
+
+// Assinatura recebe os eventos dos tópicos assinados até ser cancelada. O canal é fechado
+// no cancelamento
+type Assinatura struct {
+	Eventos  chan Evento
+	cancelar func()
+	uma      sync.Once
+}
+
+// NovaAssinatura cria uma assinatura para um broker, que chama cancelar quando ela é encerrada
+func NovaAssinatura(tamanho int, cancelar func()) *Assinatura {
+	return &Assinatura{Eventos: make(chan Evento, tamanho), cancelar: cancelar}
+}
+
+// Cancelar encerra a assinatura. Cancelar de novo não faz nada
+func (a *Assinatura) Cancelar() {
+	a.uma.Do(a.cancelar)
+}
+
+// Broker é implementado pelos meios de distribuir eventos entre as conexões abertas. O broker
+// em memória atende uma instância da API; com várias instâncias ele pode ser trocado por um
+// que repasse os eventos entre elas
+type Broker interface {
+	Publicar(topico, tipo string, dados interface{})
+	// Assinar abre uma assinatura nos tópicos. Com ultimoID diferente de zero retorna também
+	// os eventos publicados depois dele; completo é falso quando parte deles se perdeu
+	Assinar(ultimoID uint64, topicos ...string) (assinatura *Assinatura, pendentes []Evento, completo bool)
+}
+
+// TopicoFeed recebe as publicações novas do feed do usuário
+func TopicoFeed(usuarioID uint64) string {
+	return fmt.Sprintf("feed:%d", usuarioID)
+}
+
+// TopicoNotificacoes recebe as notificações do usuário
+func TopicoNotificacoes(usuarioID uint64) string {
+	return fmt.Sprintf("notificacoes:%d", usuarioID)
+}
+
+// TopicoCurtidas recebe os totais de curtidas das publicações do usuário
+func TopicoCurtidas(usuarioID uint64) string {
+	return fmt.Sprintf("curtidas:%d", usuarioID)
+}
+
+// TopicoMensagens recebe as mensagens e confirmações de leitura das conversas do usuário
+func TopicoMensagens(usuarioID uint64) string {
+	return fmt.Sprintf("mensagens:%d", usuarioID)
+}
+
+// TopicoPublicacao recebe o que acontece numa publicação, como as curtidas
+func TopicoPublicacao(publicacaoID uint64) string {
+	return fmt.Sprintf("publicacao:%d", publicacaoID)
+}
+
+var broker Broker = NovaMemoria()
+
+// Configurar troca o broker usado pela API
+func Configurar(b Broker) {
+	broker = b
+}
+
+// Publicar entrega o evento às assinaturas do tópico pelo broker configurado
+func Publicar(topico, tipo string, dados interface{}) {
+	broker.Publicar(topico, tipo, dados)
+}
+
+// Assinar abre uma assinatura nos tópicos pelo broker configurado
+func Assinar(ultimoID uint64, topicos ...string) (*Assinatura, []Evento, bool) {
+	return broker.Assinar(ultimoID, topicos...)
+}
@@ -0,0 +1,136 @@
+package eventos
+
+import (
+	"sync"
+	"time"
+)
+
+// eventosGuardados é quantos eventos recentes, somando todos os tópicos, ficam em memória para
+// quem reconecta com Last-Event-ID
+const eventosGuardados = 10000
+
+// idadeMaxima é por quanto tempo um evento fica guardado para quem reconecta
+const idadeMaxima = 10 * time.Minute
+
+// tamanhoDoCanal é quantos eventos uma assinatura pode acumular sem ler antes de perder eventos
+const tamanhoDoCanal = 32
+
+// guardado é um evento recente junto do tópico em que foi publicado
+type guardado struct {
+	topico string
+	evento Evento
+}
+
+// Memoria distribui os eventos entre as conexões abertas na própria instância da API. Os
+// eventos recentes de todos os tópicos ficam num único buffer circular, então a memória usada
+// não cresce com a quantidade de tópicos
+type Memoria struct {
+	mutex      sync.Mutex
+	ultimoID   uint64
+	assinantes map[string]map[*Assinatura]struct{}
+	recentes   []guardado
+	inicio     int
+	total      int
+	// descartadoID é o ID do evento mais novo que já saiu do buffer
+	descartadoID uint64
+}
+
+// NovaMemoria cria um broker em memória sem assinantes
+func NovaMemoria() *Memoria {
+	return &Memoria{
+		assinantes: map[string]map[*Assinatura]struct{}{},
+		recentes:   make([]guardado, eventosGuardados),
+	}
+}
+
+// Publicar entrega o evento às assinaturas do tópico e o guarda para quem reconectar
+func (m *Memoria) Publicar(topico, tipo string, dados interface{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.ultimoID++
+	evento := Evento{ID: m.ultimoID, Tipo: tipo, Dados: dados, Em: time.Now()}
+	m.guardar(topico, evento)
+
+	for assinatura := range m.assinantes[topico] {
+		select {
+		case assinatura.Eventos <- evento:
+		default:
+			// Um cliente lento não pode travar os demais; ele perde o evento e ressincroniza
+			// ao reconectar
+		}
+	}
+}
+
+// guardar põe o evento no buffer, descartando os vencidos e, com o buffer cheio, o mais antigo
+func (m *Memoria) guardar(topico string, evento Evento) {
+	limite := evento.Em.Add(-idadeMaxima)
+	for m.total > 0 && m.recentes[m.inicio].evento.Em.Before(limite) {
+		m.descartarMaisAntigo()
+	}
+	if m.total == len(m.recentes) {
+		m.descartarMaisAntigo()
+	}
+
+	fim := (m.inicio + m.total) % len(m.recentes)
+	m.recentes[fim] = guardado{topico: topico, evento: evento}
+	m.total++
+}
+
+func (m *Memoria) descartarMaisAntigo() {
+	m.descartadoID = m.recentes[m.inicio].evento.ID
+	// Zera a posição para os dados do evento não ficarem presos no buffer
+	m.recentes[m.inicio] = guardado{}
+	m.inicio = (m.inicio + 1) % len(m.recentes)
+	m.total--
+}
+
+// Assinar abre uma assinatura nos tópicos, com os eventos perdidos desde ultimoID
+func (m *Memoria) Assinar(ultimoID uint64, topicos ...string) (*Assinatura, []Evento, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var assinatura *Assinatura
+	assinatura = NovaAssinatura(tamanhoDoCanal, func() { m.cancelar(assinatura, topicos) })
+
+	assinados := map[string]bool{}
+	for _, topico := range topicos {
+		if m.assinantes[topico] == nil {
+			m.assinantes[topico] = map[*Assinatura]struct{}{}
+		}
+		m.assinantes[topico][assinatura] = struct{}{}
+		assinados[topico] = true
+	}
+
+	if ultimoID == 0 {
+		return assinatura, nil, true
+	}
+
+	// Um ID maior que o último publicado vem de antes de a API reiniciar. Como o buffer é
+	// compartilhado, não dá para saber se o evento descartado era de um dos tópicos, e na
+	// dúvida o cliente ressincroniza
+	completo := ultimoID <= m.ultimoID && ultimoID >= m.descartadoID
+	var pendentes []Evento
+	for i := 0; i < m.total; i++ {
+		recente := m.recentes[(m.inicio+i)%len(m.recentes)]
+		if recente.evento.ID > ultimoID && assinados[recente.topico] {
+			pendentes = append(pendentes, recente.evento)
+		}
+	}
+
+	return assinatura, pendentes, completo
+}
+
+func (m *Memoria) cancelar(assinatura *Assinatura, topicos []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, topico := range topicos {
+		delete(m.assinantes[topico], assinatura)
+		if len(m.assinantes[topico]) == 0 {
+			delete(m.assinantes, topico)
+		}
+	}
+
+	close(assinatura.Eventos)
+}
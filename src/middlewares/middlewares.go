@@ -2,7 +2,10 @@ package middlewares
 
 import (
 	"api/src/autenticacao"
+	"api/src/banco"
+	"api/src/repositorio"
 	"api/src/respostas"
+	"errors"
 	"log"
 	"net/http"
 )
@@ -18,10 +21,58 @@ func Logger(proximaFuncao http.HandlerFunc) http.HandlerFunc {
 // Autenticar verifica se o usuário fazendo a requisição está autenticado
 func Autenticar(proximaFuncao http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if erro := autenticacao.ValidarToken(r); erro != nil {
+		permissoes, viaCookie, erro := autenticacao.LerToken(r)
+		if erro != nil {
 			respostas.Erro(w, http.StatusUnauthorized, erro)
 			return
 		}
-		proximaFuncao(w, r)
+
+		if viaCookie && !metodoSeguro(r.Method) {
+			if erro = autenticacao.ValidarCSRF(r); erro != nil {
+				respostas.Erro(w, http.StatusForbidden, erro)
+				return
+			}
+		}
+
+		usuario, erro := permissoes.Usuario()
+		if erro != nil {
+			respostas.Erro(w, http.StatusUnauthorized, erro)
+			return
+		}
+
+		ativa, erro := verificarSessao(usuario)
+		if erro != nil {
+			respostas.Erro(w, http.StatusInternalServerError, erro)
+			return
+		}
+
+		if !ativa {
+			respostas.Erro(w, http.StatusUnauthorized, errors.New("Sessão encerrada, faça login novamente"))
+			return
+		}
+		proximaFuncao(w, autenticacao.ComUsuario(r, usuario))
+	}
+}
+
+// verificarSessao garante que a sessão do token não foi revogada e registra o acesso
+func verificarSessao(usuario autenticacao.UsuarioAutenticado) (bool, error) {
+	db, erro := banco.Conectar()
+	if erro != nil {
+		return false, erro
 	}
+	defer db.Close()
+
+	repositorio := repositorio.NovoRepositorioDeSessoes(db)
+
+	ativa, erro := repositorio.EstaAtiva(usuario.TokenID, usuario.ID)
+	if erro != nil || !ativa {
+		return false, erro
+	}
+
+	return true, repositorio.RegistrarAcesso(usuario.TokenID)
+}
+
+// metodoSeguro informa se o método HTTP não altera dados e dispensa a verificação CSRF
+func metodoSeguro(metodo string) bool {
+	return metodo == http.MethodGet || metodo == http.MethodHead || metodo == http.MethodOptions
 }
@@ -0,0 +1,115 @@
+package midia
+
+import (
+	"api/src/config"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrNaoEncontrado é retornado quando a chave não existe no armazenamento
+var ErrNaoEncontrado = errors.New("Arquivo não encontrado")
+
+// Armazenamento é implementado pelos lugares onde os arquivos das mídias ficam guardados.
+// As chaves são derivadas do hash do conteúdo, então salvar a mesma chave duas vezes é inofensivo
+type Armazenamento interface {
+	Salvar(chave string, conteudo []byte, tipo string) error
+	Abrir(chave string) (io.ReadCloser, error)
+	Remover(chave string) error
+}
+
+var armazenamento Armazenamento = Local{Diretorio: "midias"}
+
+// Carregar escolhe o armazenamento conforme a configuração
+func Carregar() error {
+	switch config.MidiaArmazenamento {
+	case "s3":
+		if config.S3Endpoint == "" || config.S3Bucket == "" {
+			return errors.New("S3_ENDPOINT e S3_BUCKET são obrigatórios com MIDIA_ARMAZENAMENTO=s3")
+		}
+		armazenamento = S3{
+			Endpoint:     config.S3Endpoint,
+			Regiao:       config.S3Regiao,
+			Bucket:       config.S3Bucket,
+			ChaveAcesso:  config.S3ChaveAcesso,
+			ChaveSecreta: config.S3ChaveSecreta,
+		}
+	default:
+		armazenamento = Local{Diretorio: config.MidiaDiretorio}
+	}
+	return nil
+}
+
+// Chave monta o endereço de uma variante da imagem no armazenamento
+func Chave(hash, variante, extensao string) string {
+	return fmt.Sprintf("%s/%s/%s.%s", hash[:2], hash, variante, ExtensaoDaVariante(extensao, variante))
+}
+
+// Guardar salva todas as variantes da imagem
+func Guardar(imagem *Imagem) error {
+	for variante, conteudo := range imagem.Variantes {
+		extensao := ExtensaoDaVariante(imagem.Extensao, variante)
+		if erro := armazenamento.Salvar(Chave(imagem.Hash, variante, imagem.Extensao), conteudo, TipoDaExtensao(extensao)); erro != nil {
+			return erro
+		}
+	}
+	return nil
+}
+
+// trava é o mutex de um hash, removido do mapa quando ninguém mais o usa
+type trava struct {
+	sync.Mutex
+	usos int
+}
+
+var (
+	travasMutex sync.Mutex
+	travas      = map[string]*trava{}
+)
+
+// Travar impede que um envio e uma exclusão do mesmo conteúdo se cruzem: quem envia segura a
+// trava até registrar a mídia e quem exclui até apagar os arquivos. Vale para uma instância da
+// API. Retorna a função que solta a trava
+func Travar(hash string) func() {
+	travasMutex.Lock()
+	t := travas[hash]
+	if t == nil {
+		t = &trava{}
+		travas[hash] = t
+	}
+	t.usos++
+	travasMutex.Unlock()
+
+	t.Lock()
+	return func() {
+		t.Unlock()
+
+		travasMutex.Lock()
+		t.usos--
+		if t.usos == 0 {
+			delete(travas, hash)
+		}
+		travasMutex.Unlock()
+	}
+}
+
+// Abrir lê uma variante de uma imagem guardada
+func Abrir(hash, variante, extensao string) (io.ReadCloser, error) {
+	return armazenamento.Abrir(Chave(hash, variante, extensao))
+}
+
+// Apagar remove todas as variantes de uma imagem
+func Apagar(hash, extensao string) error {
+	variantes := []string{VarianteOriginal}
+	for variante := range Miniaturas {
+		variantes = append(variantes, variante)
+	}
+
+	for _, variante := range variantes {
+		if erro := armazenamento.Remover(Chave(hash, variante, extensao)); erro != nil && !errors.Is(erro, ErrNaoEncontrado) {
+			return erro
+		}
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package midia
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+// VarianteOriginal é a imagem enviada, reprocessada sem metadados
+const VarianteOriginal = "original"
+
+// Miniaturas são as variantes reduzidas geradas para cada imagem, com o maior lado em pixels
+var Miniaturas = map[string]int{
+	"pequena": 160,
+	"media":   640,
+	"grande":  1280,
+}
+
+// maximoPixels evita que imagens pequenas em bytes e gigantes em pixels esgotem a memória. Nos
+// GIFs ele vale para a soma dos quadros
+const maximoPixels = 40_000_000
+
+// maximoQuadros limita os quadros de um GIF, que custam memória mesmo quando são minúsculos
+const maximoQuadros = 1000
+
+// ErrFormatoNaoSuportado é retornado para arquivos que não são JPEG, PNG ou GIF
+var ErrFormatoNaoSuportado = errors.New("Formato de arquivo não suportado, envie JPEG, PNG ou GIF")
+
+// Imagem é o resultado do processamento de um arquivo enviado
+type Imagem struct {
+	Hash      string
+	Tipo      string
+	Extensao  string
+	Largura   int
+	Altura    int
+	Variantes map[string][]byte
+}
+
+// Processar identifica o tipo pelo conteúdo (e não pela extensão ou cabeçalho do cliente),
+// decodifica e recodifica a imagem, o que descarta EXIF e outros metadados, e gera as
+// miniaturas. O hash do original recodificado é o endereço da imagem no armazenamento
+func Processar(conteudo []byte) (*Imagem, error) {
+	tipo := http.DetectContentType(conteudo)
+	if tipo != "image/jpeg" && tipo != "image/png" && tipo != "image/gif" {
+		return nil, ErrFormatoNaoSuportado
+	}
+
+	configuracao, _, erro := image.DecodeConfig(bytes.NewReader(conteudo))
+	if erro != nil {
+		return nil, ErrFormatoNaoSuportado
+	}
+
+	if configuracao.Width*configuracao.Height > maximoPixels {
+		return nil, errors.New("A imagem tem pixels demais")
+	}
+
+	imagem := &Imagem{
+		Tipo:      tipo,
+		Largura:   configuracao.Width,
+		Altura:    configuracao.Height,
+		Variantes: map[string][]byte{},
+	}
+
+	var quadro image.Image
+
+	if tipo == "image/gif" {
+		// Cada quadro é decodificado na memória, então o tamanho da tela não basta
+		quadros, pixels, erro := contarQuadros(conteudo)
+		if erro != nil {
+			return nil, ErrFormatoNaoSuportado
+		}
+		if quadros > maximoQuadros {
+			return nil, errors.New("O GIF tem quadros demais")
+		}
+		if pixels > maximoPixels {
+			return nil, errors.New("A imagem tem pixels demais")
+		}
+
+		// GIFs são recodificados quadro a quadro para manter a animação
+		animacao, erro := gif.DecodeAll(bytes.NewReader(conteudo))
+		if erro != nil {
+			return nil, ErrFormatoNaoSuportado
+		}
+
+		var original bytes.Buffer
+		if erro = gif.EncodeAll(&original, animacao); erro != nil {
+			return nil, erro
+		}
+
+		imagem.Extensao = "gif"
+		imagem.Variantes[VarianteOriginal] = original.Bytes()
+		quadro = animacao.Image[0]
+	} else {
+		quadro, _, erro = image.Decode(bytes.NewReader(conteudo))
+		if erro != nil {
+			return nil, ErrFormatoNaoSuportado
+		}
+
+		original, extensao, erro := codificar(quadro, tipo)
+		if erro != nil {
+			return nil, erro
+		}
+
+		imagem.Extensao = extensao
+		imagem.Variantes[VarianteOriginal] = original
+	}
+
+	for variante, lado := range Miniaturas {
+		// Miniaturas de GIF usam o primeiro quadro em PNG
+		tipoMiniatura := tipo
+		if tipo == "image/gif" {
+			tipoMiniatura = "image/png"
+		}
+
+		miniatura, _, erro := codificar(reduzir(quadro, lado), tipoMiniatura)
+		if erro != nil {
+			return nil, erro
+		}
+		imagem.Variantes[variante] = miniatura
+	}
+
+	soma := sha256.Sum256(imagem.Variantes[VarianteOriginal])
+	imagem.Hash = hex.EncodeToString(soma[:])
+
+	return imagem, nil
+}
+
+// contarQuadros percorre os blocos do GIF sem decodificar as imagens e retorna quantos quadros
+// ele tem e a soma dos pixels deles. Para de contar ao passar dos limites
+func contarQuadros(conteudo []byte) (quadros int, pixels int, erro error) {
+	errTruncado := errors.New("GIF truncado")
+
+	// Cabeçalho e descritor da tela, seguidos da tabela de cores global quando há
+	posicao := 13
+	if len(conteudo) < posicao {
+		return 0, 0, errTruncado
+	}
+	if conteudo[10]&0x80 != 0 {
+		posicao += 3 << (conteudo[10]&0x07 + 1)
+	}
+
+	// pularSubblocos avança sobre uma sequência de sub-blocos terminada por um de tamanho zero
+	pularSubblocos := func() bool {
+		for posicao < len(conteudo) {
+			tamanho := int(conteudo[posicao])
+			posicao += 1 + tamanho
+			if tamanho == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	for posicao < len(conteudo) {
+		switch conteudo[posicao] {
+		case 0x21: // extensão
+			posicao += 2
+			if !pularSubblocos() {
+				return 0, 0, errTruncado
+			}
+
+		case 0x2C: // descritor de imagem
+			if posicao+10 > len(conteudo) {
+				return 0, 0, errTruncado
+			}
+			largura := int(conteudo[posicao+5]) | int(conteudo[posicao+6])<<8
+			altura := int(conteudo[posicao+7]) | int(conteudo[posicao+8])<<8
+			empacotado := conteudo[posicao+9]
+
+			quadros++
+			pixels += largura * altura
+			if quadros > maximoQuadros || pixels > maximoPixels {
+				return quadros, pixels, nil
+			}
+
+			posicao += 10
+			if empacotado&0x80 != 0 {
+				posicao += 3 << (empacotado&0x07 + 1)
+			}
+			// Tamanho mínimo do código LZW e os dados da imagem
+			posicao++
+			if !pularSubblocos() {
+				return 0, 0, errTruncado
+			}
+
+		case 0x3B: // fim do arquivo
+			return quadros, pixels, nil
+
+		default:
+			return 0, 0, errors.New("Bloco de GIF desconhecido")
+		}
+	}
+
+	return 0, 0, errTruncado
+}
+
+// ExtensaoDaVariante retorna a extensão do arquivo de uma variante da imagem
+func ExtensaoDaVariante(extensao, variante string) string {
+	if extensao == "gif" && variante != VarianteOriginal {
+		return "png"
+	}
+	return extensao
+}
+
+// TipoDaExtensao retorna o content type de uma extensão gerada pelo processamento
+func TipoDaExtensao(extensao string) string {
+	switch extensao {
+	case "jpg":
+		return "image/jpeg"
+	case "gif":
+		return "image/gif"
+	default:
+		return "image/png"
+	}
+}
+
+func codificar(imagem image.Image, tipo string) ([]byte, string, error) {
+	var buffer bytes.Buffer
+
+	if tipo == "image/jpeg" {
+		if erro := jpeg.Encode(&buffer, imagem, &jpeg.Options{Quality: 85}); erro != nil {
+			return nil, "", erro
+		}
+		return buffer.Bytes(), "jpg", nil
+	}
+
+	if erro := png.Encode(&buffer, imagem); erro != nil {
+		return nil, "", erro
+	}
+	return buffer.Bytes(), "png", nil
+}
+
+// reduzir redimensiona a imagem para que o maior lado tenha no máximo o tamanho informado
+func reduzir(imagem image.Image, lado int) image.Image {
+	limites := imagem.Bounds()
+	largura, altura := limites.Dx(), limites.Dy()
+
+	if largura <= lado && altura <= lado {
+		return imagem
+	}
+
+	if largura >= altura {
+		altura = altura * lado / largura
+		largura = lado
+	} else {
+		largura = largura * lado / altura
+		altura = lado
+	}
+
+	if largura < 1 {
+		largura = 1
+	}
+	if altura < 1 {
+		altura = 1
+	}
+
+	reduzida := image.NewRGBA(image.Rect(0, 0, largura, altura))
+	draw.CatmullRom.Scale(reduzida, reduzida.Bounds(), imagem, limites, draw.Over, nil)
+	return reduzida
+}
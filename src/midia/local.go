@@ -0,0 +1,60 @@
+package midia
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Local guarda os arquivos em um diretório do servidor
+type Local struct {
+	Diretorio string
+}
+
+func (l Local) Salvar(chave string, conteudo []byte, tipo string) error {
+	caminho := filepath.Join(l.Diretorio, filepath.FromSlash(chave))
+
+	if _, erro := os.Stat(caminho); erro == nil {
+		return nil
+	}
+
+	if erro := os.MkdirAll(filepath.Dir(caminho), 0o755); erro != nil {
+		return erro
+	}
+
+	// Grava em um arquivo temporário e renomeia para que leitores nunca vejam um arquivo pela metade
+	temporario, erro := os.CreateTemp(filepath.Dir(caminho), ".envio-*")
+	if erro != nil {
+		return erro
+	}
+	defer os.Remove(temporario.Name())
+
+	if _, erro = temporario.Write(conteudo); erro != nil {
+		temporario.Close()
+		return erro
+	}
+
+	if erro = temporario.Close(); erro != nil {
+		return erro
+	}
+
+	return os.Rename(temporario.Name(), caminho)
+}
+
+func (l Local) Abrir(chave string) (io.ReadCloser, error) {
+	arquivo, erro := os.Open(filepath.Join(l.Diretorio, filepath.FromSlash(chave)))
+	if errors.Is(erro, fs.ErrNotExist) {
+		return nil, ErrNaoEncontrado
+	}
+	return arquivo, erro
+}
+
+func (l Local) Remover(chave string) error {
+	erro := os.Remove(filepath.Join(l.Diretorio, filepath.FromSlash(chave)))
+	if errors.Is(erro, fs.ErrNotExist) {
+		return ErrNaoEncontrado
+	}
+	return erro
+}
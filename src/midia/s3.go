@@ -0,0 +1,173 @@
+package midia
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3 guarda os arquivos em um serviço compatível com S3 (AWS, MinIO), endereçando o
+// bucket no caminho da URL e assinando as requisições com AWS Signature V4
+type S3 struct {
+	Endpoint     string
+	Regiao       string
+	Bucket       string
+	ChaveAcesso  string
+	ChaveSecreta string
+}
+
+var clienteS3 = &http.Client{Timeout: 30 * time.Second}
+
+func (s S3) Salvar(chave string, conteudo []byte, tipo string) error {
+	resposta, erro := s.requisitar(http.MethodPut, chave, conteudo, tipo)
+	if erro != nil {
+		return erro
+	}
+	defer resposta.Body.Close()
+
+	if resposta.StatusCode != http.StatusOK {
+		return erroS3(resposta)
+	}
+	return nil
+}
+
+func (s S3) Abrir(chave string) (io.ReadCloser, error) {
+	resposta, erro := s.requisitar(http.MethodGet, chave, nil, "")
+	if erro != nil {
+		return nil, erro
+	}
+
+	switch resposta.StatusCode {
+	case http.StatusOK:
+		return resposta.Body, nil
+	case http.StatusNotFound:
+		resposta.Body.Close()
+		return nil, ErrNaoEncontrado
+	default:
+		defer resposta.Body.Close()
+		return nil, erroS3(resposta)
+	}
+}
+
+func (s S3) Remover(chave string) error {
+	resposta, erro := s.requisitar(http.MethodDelete, chave, nil, "")
+	if erro != nil {
+		return erro
+	}
+	defer resposta.Body.Close()
+
+	switch resposta.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return ErrNaoEncontrado
+	default:
+		return erroS3(resposta)
+	}
+}
+
+func (s S3) requisitar(metodo, chave string, conteudo []byte, tipo string) (*http.Response, error) {
+	endereco, erro := url.Parse(s.Endpoint)
+	if erro != nil {
+		return nil, erro
+	}
+
+	segmentos := []string{s.Bucket}
+	for _, segmento := range strings.Split(chave, "/") {
+		segmentos = append(segmentos, url.PathEscape(segmento))
+	}
+	endereco.RawPath = "/" + strings.Join(segmentos, "/")
+	endereco.Path, _ = url.PathUnescape(endereco.RawPath)
+
+	requisicao, erro := http.NewRequest(metodo, endereco.String(), bytes.NewReader(conteudo))
+	if erro != nil {
+		return nil, erro
+	}
+
+	if tipo != "" {
+		requisicao.Header.Set("Content-Type", tipo)
+	}
+	s.assinar(requisicao, conteudo, time.Now().UTC())
+
+	return clienteS3.Do(requisicao)
+}
+
+// assinar adiciona o cabeçalho Authorization no formato AWS Signature V4
+func (s S3) assinar(requisicao *http.Request, conteudo []byte, agora time.Time) {
+	data := agora.Format("20060102")
+	dataHora := agora.Format("20060102T150405Z")
+	hashConteudo := hexSHA256(conteudo)
+
+	requisicao.Header.Set("X-Amz-Date", dataHora)
+	requisicao.Header.Set("X-Amz-Content-Sha256", hashConteudo)
+
+	cabecalhos := map[string]string{"host": requisicao.URL.Host}
+	for nome := range requisicao.Header {
+		cabecalhos[strings.ToLower(nome)] = strings.TrimSpace(requisicao.Header.Get(nome))
+	}
+
+	nomes := make([]string, 0, len(cabecalhos))
+	for nome := range cabecalhos {
+		nomes = append(nomes, nome)
+	}
+	sort.Strings(nomes)
+
+	var canonicos strings.Builder
+	for _, nome := range nomes {
+		canonicos.WriteString(nome + ":" + cabecalhos[nome] + "\n")
+	}
+	assinados := strings.Join(nomes, ";")
+
+	requisicaoCanonica := strings.Join([]string{
+		requisicao.Method,
+		requisicao.URL.EscapedPath(),
+		requisicao.URL.RawQuery,
+		canonicos.String(),
+		assinados,
+		hashConteudo,
+	}, "\n")
+
+	escopo := fmt.Sprintf("%s/%s/s3/aws4_request", data, s.Regiao)
+	textoParaAssinar := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		dataHora,
+		escopo,
+		hexSHA256([]byte(requisicaoCanonica)),
+	}, "\n")
+
+	chave := hmacSHA256([]byte("AWS4"+s.ChaveSecreta), data)
+	chave = hmacSHA256(chave, s.Regiao)
+	chave = hmacSHA256(chave, "s3")
+	chave = hmacSHA256(chave, "aws4_request")
+	assinatura := hex.EncodeToString(hmacSHA256(chave, textoParaAssinar))
+
+	requisicao.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.ChaveAcesso, escopo, assinados, assinatura,
+	))
+}
+
+func erroS3(resposta *http.Response) error {
+	corpo, _ := ioutil.ReadAll(io.LimitReader(resposta.Body, 1024))
+	return fmt.Errorf("S3 respondeu %s: %s", resposta.Status, strings.TrimSpace(string(corpo)))
+}
+
+func hexSHA256(conteudo []byte) string {
+	soma := sha256.Sum256(conteudo)
+	return hex.EncodeToString(soma[:])
+}
+
+func hmacSHA256(chave []byte, texto string) []byte {
+	mac := hmac.New(sha256.New, chave)
+	mac.Write([]byte(texto))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,99 @@
+package modelos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Tipos de conversa. Conversas diretas têm sempre os mesmos dois participantes; grupos têm
+// nome e administradores que adicionam e removem membros
+const (
+	ConversaDireta = "direta"
+	ConversaGrupo  = "grupo"
+)
+
+// tamanhoMaximoNomeGrupo é a quantidade máxima de caracteres do nome de um grupo
+const tamanhoMaximoNomeGrupo = 100
+
+// MaximoMembrosGrupo é a quantidade máxima de participantes de um grupo
+const MaximoMembrosGrupo = 100
+
+// Conversa representa uma conversa privada entre usuários. NaoLidas conta as mensagens dos
+// outros participantes que o usuário ainda não leu
+type Conversa struct {
+	ID             uint64         `json:"id,omitempty"`
+	Tipo           string         `json:"tipo,omitempty"`
+	Nome           string         `json:"nome,omitempty"`
+	Participantes  []Participante `json:"participantes,omitempty"`
+	UltimaMensagem *Mensagem      `json:"ultimaMensagem,omitempty"`
+	NaoLidas       uint64         `json:"naoLidas"`
+	CriadaEm       time.Time      `json:"criadaEm,omitempty"`
+}
+
+// Participante é um membro da conversa. UltimaLidaID é a última mensagem que ele leu
+type Participante struct {
+	ID           uint64 `json:"id"`
+	Nick         string `json:"nick"`
+	Admin        bool   `json:"admin,omitempty"`
+	UltimaLidaID uint64 `json:"ultimaLidaId"`
+}
+
+// Grupo é o pedido de criação de um grupo
+type Grupo struct {
+	Nome          string   `json:"nome"`
+	Participantes []uint64 `json:"participantes"`
+}
+
+// Preparar valida e formata o grupo recebido. O criador entra no grupo mesmo sem estar
+// entre os participantes
+func (grupo *Grupo) Preparar(criadorID uint64) error {
+	erros := ErroDeValidacao{}
+
+	grupo.Nome = strings.Join(strings.Fields(grupo.Nome), " ")
+	if grupo.Nome == "" {
+		erros.Adicionar("nome", "O nome não pode estar em branco")
+	} else if utf8.RuneCountInString(grupo.Nome) > tamanhoMaximoNomeGrupo {
+		erros.Adicionar("nome", fmt.Sprintf("O nome pode ter no máximo %d caracteres", tamanhoMaximoNomeGrupo))
+	}
+
+	participantes := []uint64{}
+	repetidos := map[uint64]bool{criadorID: true}
+	for _, participante := range grupo.Participantes {
+		if !repetidos[participante] {
+			repetidos[participante] = true
+			participantes = append(participantes, participante)
+		}
+	}
+	grupo.Participantes = participantes
+
+	if len(grupo.Participantes) == 0 {
+		erros.Adicionar("participantes", "Informe ao menos um participante além de você")
+	} else if len(grupo.Participantes)+1 > MaximoMembrosGrupo {
+		erros.Adicionar("participantes", fmt.Sprintf("Um grupo pode ter no máximo %d participantes", MaximoMembrosGrupo))
+	}
+
+	return erros.Erro()
+}
+
+// Outros retorna os participantes da conversa além do usuário
+func (conversa Conversa) Outros(usuarioID uint64) []Participante {
+	var outros []Participante
+	for _, participante := range conversa.Participantes {
+		if participante.ID != usuarioID {
+			outros = append(outros, participante)
+		}
+	}
+	return outros
+}
+
+// Participa informa se o usuário é membro da conversa
+func (conversa Conversa) Participa(usuarioID uint64) (Participante, bool) {
+	for _, participante := range conversa.Participantes {
+		if participante.ID == usuarioID {
+			return participante, true
+		}
+	}
+	return Participante{}, false
+}
@@ -0,0 +1,20 @@
+package modelos
+
+import "strings"
+
+// Credenciais representa o formato da requisição de login. O campo login aceita email ou
+// nick; email continua aceito para os clientes que já enviam esse campo
+type Credenciais struct {
+	Login string `json:"login,omitempty"`
+	Email string `json:"email,omitempty"`
+	Senha string `json:"senha"`
+}
+
+// Identificador retorna o email ou nick informado, normalizado para a busca
+func (c Credenciais) Identificador() string {
+	identificador := c.Login
+	if identificador == "" {
+		identificador = c.Email
+	}
+	return strings.ToLower(strings.TrimSpace(identificador))
+}
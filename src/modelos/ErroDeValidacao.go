@@ -0,0 +1,41 @@
+package modelos
+
+import (
+	"sort"
+	"strings"
+)
+
+// ErroDeValidacao reúne as mensagens de validação de cada campo da requisição
+type ErroDeValidacao map[string][]string
+
+// Adicionar registra uma mensagem de validação para o campo
+func (e ErroDeValidacao) Adicionar(campo, mensagem string) {
+	e[campo] = append(e[campo], mensagem)
+}
+
+// Campos retorna as mensagens agrupadas por campo
+func (e ErroDeValidacao) Campos() map[string][]string {
+	return e
+}
+
+// Erro retorna nil quando nenhum campo tem mensagens, para ser usado como retorno das validações
+func (e ErroDeValidacao) Erro() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e ErroDeValidacao) Error() string {
+	campos := make([]string, 0, len(e))
+	for campo := range e {
+		campos = append(campos, campo)
+	}
+	sort.Strings(campos)
+
+	var mensagens []string
+	for _, campo := range campos {
+		mensagens = append(mensagens, e[campo]...)
+	}
+	return strings.Join(mensagens, ". ")
+}
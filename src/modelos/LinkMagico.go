@@ -0,0 +1,7 @@
+package modelos
+
+//LinkMagico representa o formato das requisições de login sem senha
+type LinkMagico struct {
+	Email string `json:"email,omitempty"`
+	Token string `json:"token,omitempty"`
+}
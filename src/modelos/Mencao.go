@@ -0,0 +1,49 @@
+package modelos
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Mencao representa um @nick resolvido dentro de uma publicação. Inicio e Fim são posições
+// em caracteres no campo indicado, com o @ incluído e o Fim exclusivo
+type Mencao struct {
+	UsuarioID uint64 `json:"usuarioId"`
+	Nick      string `json:"nick"`
+	Campo     string `json:"campo"`
+	Inicio    int    `json:"inicio"`
+	Fim       int    `json:"fim"`
+}
+
+// padraoMencao encontra um @ no começo do texto ou depois de algo que não faça parte de um
+// nick, o que deixa emails de fora
+var padraoMencao = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.\-@])(@[\p{L}\p{N}_.\-]+)`)
+
+// ExtrairMencoes encontra os @nick do título e do conteúdo. Os usuários ainda não estão
+// resolvidos, então só Nick, Campo, Inicio e Fim vêm preenchidos
+func (p Publicacao) ExtrairMencoes() []Mencao {
+	return append(extrairMencoes("titulo", p.Titulo), extrairMencoes("conteudo", p.Conteudo)...)
+}
+
+func extrairMencoes(campo, texto string) []Mencao {
+	var mencoes []Mencao
+
+	for _, posicoes := range padraoMencao.FindAllStringSubmatchIndex(texto, -1) {
+		inicio, fim := posicoes[2], posicoes[3]
+
+		// Pontuação no fim costuma ser da frase e não do nick, como em "valeu @ana."
+		mencao := strings.TrimRight(texto[inicio:fim], ".-")
+		if len(mencao) <= 1 {
+			continue
+		}
+
+		mencoes = append(mencoes, Mencao{
+			Nick:   mencao[1:],
+			Campo:  campo,
+			Inicio: utf8.RuneCountInString(texto[:inicio]),
+			Fim:    utf8.RuneCountInString(texto[:inicio]) + utf8.RuneCountInString(mencao),
+		})
+	}
+	return mencoes
+}
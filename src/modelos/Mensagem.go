@@ -0,0 +1,46 @@
+package modelos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// tamanhoMaximoMensagem é a quantidade máxima de caracteres de uma mensagem
+const tamanhoMaximoMensagem = 2000
+
+// Tipos de mensagem. Mensagens do sistema registram as mudanças de membros de um grupo e têm
+// como autor quem fez a mudança
+const (
+	MensagemDoUsuario = "usuario"
+	MensagemDoSistema = "sistema"
+)
+
+// Mensagem representa uma mensagem enviada numa conversa. Lida informa se todos os outros
+// participantes já a leram
+type Mensagem struct {
+	ID         uint64    `json:"id,omitempty"`
+	ConversaID uint64    `json:"conversaId,omitempty"`
+	Tipo       string    `json:"tipo,omitempty"`
+	AutorID    uint64    `json:"autorId,omitempty"`
+	AutorNick  string    `json:"autorNick,omitempty"`
+	Conteudo   string    `json:"conteudo"`
+	Lida       bool      `json:"lida"`
+	CriadaEm   time.Time `json:"criadaEm,omitempty"`
+}
+
+// Preparar valida e formata a mensagem recebida
+func (mensagem *Mensagem) Preparar() error {
+	erros := ErroDeValidacao{}
+
+	mensagem.Tipo = MensagemDoUsuario
+	mensagem.Conteudo = strings.TrimSpace(mensagem.Conteudo)
+	if mensagem.Conteudo == "" {
+		erros.Adicionar("conteudo", "O conteudo não pode estar em branco")
+	} else if utf8.RuneCountInString(mensagem.Conteudo) > tamanhoMaximoMensagem {
+		erros.Adicionar("conteudo", fmt.Sprintf("A mensagem pode ter no máximo %d caracteres", tamanhoMaximoMensagem))
+	}
+
+	return erros.Erro()
+}
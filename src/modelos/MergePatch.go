@@ -0,0 +1,81 @@
+package modelos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// AplicarMergePatch aplica um JSON Merge Patch (RFC 7396) sobre o valor informado. Campos
+// ausentes no patch continuam iguais, campos com null voltam ao valor vazio e os demais são
+// substituídos. Retorna os campos presentes no patch, para que só eles sejam validados
+func AplicarMergePatch(valor interface{}, patch []byte, permitidos ...string) ([]string, error) {
+	var alteracoes map[string]json.RawMessage
+	if erro := json.Unmarshal(patch, &alteracoes); erro != nil {
+		return nil, errors.New("O corpo deve ser um objeto JSON")
+	}
+
+	campos := make([]string, 0, len(alteracoes))
+	for campo := range alteracoes {
+		if !contem(permitidos, campo) {
+			return nil, fmt.Errorf("O campo %s não pode ser alterado", campo)
+		}
+		campos = append(campos, campo)
+	}
+	sort.Strings(campos)
+
+	// Campos com null voltam ao valor vazio; os demais são decodificados sobre o valor atual
+	for campo, alteracao := range alteracoes {
+		if string(alteracao) == "null" {
+			zerarCampo(valor, campo)
+		}
+	}
+
+	if erro := json.Unmarshal(patch, valor); erro != nil {
+		return nil, erro
+	}
+
+	return campos, nil
+}
+
+// zerarCampo volta ao valor vazio o campo da struct cujo nome no JSON é o informado
+func zerarCampo(valor interface{}, campo string) {
+	estrutura := reflect.ValueOf(valor).Elem()
+	tipo := estrutura.Type()
+
+	for i := 0; i < tipo.NumField(); i++ {
+		nome := strings.Split(tipo.Field(i).Tag.Get("json"), ",")[0]
+		if nome == campo {
+			estrutura.Field(i).Set(reflect.Zero(tipo.Field(i).Type))
+			return
+		}
+	}
+}
+
+// apenasCampos descarta as mensagens de validação dos campos que não foram alterados
+func apenasCampos(erro error, campos []string) error {
+	var erros ErroDeValidacao
+	if !errors.As(erro, &erros) {
+		return erro
+	}
+
+	filtrado := ErroDeValidacao{}
+	for campo, mensagens := range erros {
+		if contem(campos, campo) {
+			filtrado[campo] = mensagens
+		}
+	}
+	return filtrado.Erro()
+}
+
+func contem(valores []string, valor string) bool {
+	for _, v := range valores {
+		if v == valor {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+package modelos
+
+import "time"
+
+// Midia representa uma imagem enviada por um usuário
+type Midia struct {
+	ID        uint64            `json:"id,omitempty"`
+	UsuarioID uint64            `json:"usuarioId,omitempty"`
+	Hash      string            `json:"hash,omitempty"`
+	Tipo      string            `json:"tipo,omitempty"`
+	Extensao  string            `json:"-"`
+	Largura   int               `json:"largura,omitempty"`
+	Altura    int               `json:"altura,omitempty"`
+	Tamanho   int               `json:"tamanho,omitempty"`
+	URLs      map[string]string `json:"urls,omitempty"`
+	CriadaEm  time.Time         `json:"criadaEm,omitempty"`
+}
@@ -0,0 +1,74 @@
+package modelos
+
+import (
+	"fmt"
+	"time"
+)
+
+// Tipos de notificação
+const (
+	NotificacaoSeguidor    = "seguidor"
+	NotificacaoSolicitacao = "solicitacao"
+	NotificacaoCurtida     = "curtida"
+	NotificacaoMencao      = "mencao"
+)
+
+// TiposDeNotificacao são os tipos que o usuário pode ligar e desligar nas preferências
+var TiposDeNotificacao = []string{NotificacaoSeguidor, NotificacaoSolicitacao, NotificacaoCurtida, NotificacaoMencao}
+
+// Notificacao representa um aviso para o usuário. Avisos do mesmo tipo sobre a mesma
+// publicação são agrupados enquanto não são lidos: AtorID é o mais recente e Atores o total
+type Notificacao struct {
+	ID           uint64    `json:"id"`
+	UsuarioID    uint64    `json:"-"`
+	Tipo         string    `json:"tipo"`
+	PublicacaoID *uint64   `json:"publicacaoId,omitempty"`
+	AtorID       uint64    `json:"atorId"`
+	AtorNick     string    `json:"atorNick"`
+	Atores       uint64    `json:"atores"`
+	Texto        string    `json:"texto"`
+	Lida         bool      `json:"lida"`
+	CriadaEm     time.Time `json:"criadaEm"`
+	AtualizadaEm time.Time `json:"atualizadaEm"`
+}
+
+// Descrever monta o texto da notificação, como "ana e mais 5 pessoas curtiram sua publicação"
+func (n *Notificacao) Descrever() {
+	quem := n.AtorNick
+	if n.Atores > 1 {
+		outras := "pessoas"
+		if n.Atores == 2 {
+			outras = "pessoa"
+		}
+		quem = fmt.Sprintf("%s e mais %d %s", n.AtorNick, n.Atores-1, outras)
+	}
+
+	plural := n.Atores > 1
+	switch n.Tipo {
+	case NotificacaoSeguidor:
+		n.Texto = quem + escolher(plural, " começaram a seguir você", " começou a seguir você")
+	case NotificacaoSolicitacao:
+		n.Texto = quem + escolher(plural, " pediram para seguir você", " pediu para seguir você")
+	case NotificacaoCurtida:
+		n.Texto = quem + escolher(plural, " curtiram sua publicação", " curtiu sua publicação")
+	case NotificacaoMencao:
+		n.Texto = quem + escolher(plural, " mencionaram você em uma publicação", " mencionou você em uma publicação")
+	}
+}
+
+// TipoDeNotificacaoValido informa se o tipo existe
+func TipoDeNotificacaoValido(tipo string) bool {
+	for _, valido := range TiposDeNotificacao {
+		if valido == tipo {
+			return true
+		}
+	}
+	return false
+}
+
+func escolher(condicao bool, verdadeiro, falso string) string {
+	if condicao {
+		return verdadeiro
+	}
+	return falso
+}
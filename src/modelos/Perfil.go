@@ -0,0 +1,79 @@
+package modelos
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FormatoData é o formato das datas sem hora trocadas com os clientes
+const FormatoData = "2006-01-02"
+
+// limitesPerfil é a quantidade máxima de caracteres de cada campo do perfil
+var limitesPerfil = map[string]int{
+	"bio":         300,
+	"avatarUrl":   255,
+	"capaUrl":     255,
+	"localizacao": 100,
+	"website":     255,
+}
+
+// camposDoPerfil associa o nome de cada campo opcional do perfil ao seu valor no usuário
+func (u *Usuario) camposDoPerfil() map[string]**string {
+	return map[string]**string{
+		"bio":            &u.Bio,
+		"avatarUrl":      &u.AvatarURL,
+		"capaUrl":        &u.CapaURL,
+		"localizacao":    &u.Localizacao,
+		"website":        &u.Website,
+		"dataNascimento": &u.DataNascimento,
+	}
+}
+
+// validarPerfil valida apenas os campos do perfil que foram enviados. Um campo vazio
+// significa que o usuário quer remover a informação
+func (u *Usuario) validarPerfil(erros ErroDeValidacao) {
+	campos := u.camposDoPerfil()
+
+	for campo, limite := range limitesPerfil {
+		valor := *campos[campo]
+		if valor != nil && utf8.RuneCountInString(strings.TrimSpace(*valor)) > limite {
+			erros.Adicionar(campo, fmt.Sprintf("O %s deve ter no máximo %d caracteres", campo, limite))
+		}
+	}
+
+	for _, campo := range []string{"avatarUrl", "capaUrl", "website"} {
+		valor := *campos[campo]
+		if valor != nil && strings.TrimSpace(*valor) != "" && !urlValida(strings.TrimSpace(*valor)) {
+			erros.Adicionar(campo, fmt.Sprintf("O %s deve ser um endereço http ou https", campo))
+		}
+	}
+
+	if u.DataNascimento != nil && strings.TrimSpace(*u.DataNascimento) != "" {
+		data, erro := time.Parse(FormatoData, strings.TrimSpace(*u.DataNascimento))
+		switch {
+		case erro != nil:
+			erros.Adicionar("dataNascimento", "A data de nascimento deve estar no formato AAAA-MM-DD")
+		case data.After(time.Now()):
+			erros.Adicionar("dataNascimento", "A data de nascimento não pode estar no futuro")
+		case data.Year() < 1900:
+			erros.Adicionar("dataNascimento", "A data de nascimento não é válida")
+		}
+	}
+}
+
+func (u *Usuario) formatarPerfil() {
+	for _, valor := range u.camposDoPerfil() {
+		if *valor != nil {
+			formatado := strings.TrimSpace(**valor)
+			*valor = &formatado
+		}
+	}
+}
+
+func urlValida(endereco string) bool {
+	u, erro := url.Parse(endereco)
+	return erro == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
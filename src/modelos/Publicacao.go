@@ -1,23 +1,44 @@
 package modelos
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 	"time"
 )
 
-// Publicacao representa uma publicação feita por um usuário
+// Publicacao representa uma publicação feita por um usuário. CitacaoID aponta a publicação
+// citada e fica nulo quando ela é apagada; RepostadaPor identifica quem colocou a publicação
+// no feed quando ela chega por uma repostagem
 type Publicacao struct {
-	ID        uint64    `json:"id,omitempty"`
-	Titulo    string    `json:"titulo,omitempty"`
-	Conteudo  string    `json:"conteudo,omitempty"`
-	AutorID   uint64    `json:"autorId,omitempty"`
-	AutorNick string    `json:"autorNick,omitempty"`
-	Curtidas  uint64    `json:"curtidas"`
-	CriadaEm  time.Time `json:"criadaEm,omitempty"`
+	ID               uint64      `json:"id,omitempty"`
+	Titulo           string      `json:"titulo,omitempty"`
+	Conteudo         string      `json:"conteudo,omitempty"`
+	AutorID          uint64      `json:"autorId,omitempty"`
+	AutorNick        string      `json:"autorNick,omitempty"`
+	Curtidas         uint64      `json:"curtidas"`
+	Repostagens      uint64      `json:"repostagens"`
+	Midias           []uint64    `json:"midias,omitempty"`
+	Tags             []string    `json:"tags,omitempty"`
+	Mencoes          []Mencao    `json:"mencoes,omitempty"`
+	Visibilidade     string      `json:"visibilidade,omitempty"`
+	CitacaoID        *uint64     `json:"citacaoId,omitempty"`
+	Citacao          *Publicacao `json:"citacao,omitempty"`
+	RepostadaPorID   uint64      `json:"repostadaPorId,omitempty"`
+	RepostadaPorNick string      `json:"repostadaPorNick,omitempty"`
+	Versao           uint64      `json:"-"`
+	CriadaEm         time.Time   `json:"criadaEm,omitempty"`
 }
 
+// Visibilidades de uma publicação. Sem visibilidade informada a publicação é pública
+const (
+	VisibilidadePublica    = "publica"
+	VisibilidadeSeguidores = "seguidores"
+	VisibilidadeSomenteEu  = "somente_eu"
+)
+
+// maximoMidias é a quantidade máxima de mídias anexadas a uma publicação
+const maximoMidias = 4
+
 // Preparar vai chamar os métodos para validar e formatar o usuário recebido
 func (publicacao *Publicacao) Preparar() error {
 	if erro := publicacao.validar(); erro != nil {
@@ -29,24 +50,60 @@ func (publicacao *Publicacao) Preparar() error {
 	return nil
 }
 
-func (p *Publicacao) validar() error {
-	if erro := p.validated(p.Titulo, "Titulo"); erro != nil {
-		return erro
-	}
-	if erro := p.validated(p.Conteudo, "Conteudo"); erro != nil {
+// PrepararPatch valida e formata a publicação depois de um merge patch, considerando
+// apenas os campos que foram alterados
+func (publicacao *Publicacao) PrepararPatch(campos []string) error {
+	if erro := apenasCampos(publicacao.validar(), campos); erro != nil {
 		return erro
 	}
+
+	publicacao.formatar()
+
 	return nil
 }
 
+func (p *Publicacao) validar() error {
+	erros := ErroDeValidacao{}
+
+	p.validated(erros, p.Titulo, "titulo")
+	p.validated(erros, p.Conteudo, "conteudo")
+
+	if len(p.Midias) > maximoMidias {
+		erros.Adicionar("midias", fmt.Sprintf("Uma publicação pode ter no máximo %d mídias", maximoMidias))
+	}
+
+	anexadas := map[uint64]bool{}
+	for _, midiaID := range p.Midias {
+		if anexadas[midiaID] {
+			erros.Adicionar("midias", "A mesma mídia não pode ser anexada mais de uma vez")
+			break
+		}
+		anexadas[midiaID] = true
+	}
+
+	switch strings.TrimSpace(p.Visibilidade) {
+	case "", VisibilidadePublica, VisibilidadeSeguidores, VisibilidadeSomenteEu:
+	default:
+		erros.Adicionar("visibilidade", fmt.Sprintf("A visibilidade deve ser %s, %s ou %s",
+			VisibilidadePublica, VisibilidadeSeguidores, VisibilidadeSomenteEu))
+	}
+	return erros.Erro()
+}
+
 func (p *Publicacao) formatar() {
 	p.Titulo = strings.TrimSpace(p.Titulo)
 	p.Conteudo = strings.TrimSpace(p.Conteudo)
+
+	p.Visibilidade = strings.TrimSpace(p.Visibilidade)
+	if p.Visibilidade == "" {
+		p.Visibilidade = VisibilidadePublica
+	}
+
+	p.Tags = ExtrairHashtags(p.Titulo, p.Conteudo)
 }
 
-func (p *Publicacao) validated(text string, campo string) error {
-	if text == "" {
-		return errors.New(fmt.Sprintf("O %s não pode estar em branco", campo))
+func (p *Publicacao) validated(erros ErroDeValidacao, text string, campo string) {
+	if strings.TrimSpace(text) == "" {
+		erros.Adicionar(campo, fmt.Sprintf("O %s não pode estar em branco", campo))
 	}
-	return nil
 }
@@ -1,7 +1,24 @@
 package modelos
 
+import "api/src/seguranca"
+
 //Senha representa o formato da requisição de alteração de senha
 type Senha struct {
 	Nova  string `json:"nova"`
 	Atual string `json:"atual"`
 }
+
+// Validar aplica a política de senhas na senha nova
+func (s Senha) Validar(nick, email string) error {
+	erros := ErroDeValidacao{}
+
+	if s.Atual == "" {
+		erros.Adicionar("atual", "A senha atual não pode estar em branco")
+	}
+
+	for _, problema := range seguranca.ValidarSenha(s.Nova, nick, email) {
+		erros.Adicionar("nova", problema)
+	}
+
+	return erros.Erro()
+}
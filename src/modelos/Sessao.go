@@ -0,0 +1,15 @@
+package modelos
+
+import "time"
+
+// Sessao representa um login de um usuário em um dispositivo
+type Sessao struct {
+	ID             string    `json:"id"`
+	UsuarioID      uint64    `json:"-"`
+	UserAgent      string    `json:"userAgent"`
+	IP             string    `json:"ip"`
+	CriadaEm       time.Time `json:"criadaEm"`
+	UltimoAcessoEm time.Time `json:"ultimoAcessoEm"`
+	ExpiraEm       time.Time `json:"expiraEm"`
+	Atual          bool      `json:"atual"`
+}
@@ -0,0 +1,43 @@
+package modelos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// tamanhoMaximoTermo é o tamanho máximo de uma palavra ou frase silenciada
+const tamanhoMaximoTermo = 100
+
+// Silenciamento representa um usuário ou um termo que o usuário não quer ver no feed.
+// Sem ExpiraEm o silenciamento vale até ser desfeito
+type Silenciamento struct {
+	ID        uint64     `json:"id,omitempty"`
+	UsuarioID uint64     `json:"usuarioId,omitempty"`
+	Nick      string     `json:"nick,omitempty"`
+	Termo     string     `json:"termo,omitempty"`
+	ExpiraEm  *time.Time `json:"expiraEm,omitempty"`
+	CriadoEm  time.Time  `json:"criadoEm,omitempty"`
+}
+
+// Preparar valida o silenciamento; com termo informa que é um silenciamento de palavra
+func (silenciamento *Silenciamento) Preparar(termo bool) error {
+	erros := ErroDeValidacao{}
+
+	if termo {
+		silenciamento.Termo = strings.ToLower(strings.Join(strings.Fields(silenciamento.Termo), " "))
+
+		if silenciamento.Termo == "" {
+			erros.Adicionar("termo", "O termo não pode estar em branco")
+		} else if utf8.RuneCountInString(silenciamento.Termo) > tamanhoMaximoTermo {
+			erros.Adicionar("termo", fmt.Sprintf("O termo pode ter no máximo %d caracteres", tamanhoMaximoTermo))
+		}
+	}
+
+	if silenciamento.ExpiraEm != nil && !silenciamento.ExpiraEm.After(time.Now()) {
+		erros.Adicionar("expiraEm", "A expiração precisa estar no futuro")
+	}
+
+	return erros.Erro()
+}
@@ -0,0 +1,66 @@
+package modelos
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TamanhoMaximoTag é o tamanho máximo de uma hashtag, sem o #
+const TamanhoMaximoTag = 50
+
+// Tag representa uma hashtag usada nas publicações
+type Tag struct {
+	ID          uint64 `json:"id,omitempty"`
+	Nome        string `json:"nome"`
+	Publicacoes uint64 `json:"publicacoes"`
+}
+
+// padraoHashtag encontra um # no começo do texto ou depois de algo que não seja letra,
+// número ou _, seguido do nome da tag
+var padraoHashtag = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&])#([\p{L}\p{N}_]+)`)
+
+// ExtrairHashtags retorna as hashtags dos textos normalizadas em minúsculas, sem repetições
+// e na ordem em que aparecem. Tags só com números ou longas demais são ignoradas
+func ExtrairHashtags(textos ...string) []string {
+	var tags []string
+	vistas := map[string]bool{}
+
+	for _, texto := range textos {
+		for _, encontrada := range padraoHashtag.FindAllStringSubmatch(texto, -1) {
+			tag := NormalizarTag(encontrada[1])
+			if tag == "" || vistas[tag] {
+				continue
+			}
+
+			vistas[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// NormalizarTag deixa o nome da tag no formato salvo no banco. Retorna vazio para nomes inválidos
+func NormalizarTag(nome string) string {
+	nome = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(nome), "#"))
+
+	if nome == "" || utf8.RuneCountInString(nome) > TamanhoMaximoTag {
+		return ""
+	}
+
+	temLetra := false
+	for _, caractere := range nome {
+		if !unicode.IsLetter(caractere) && !unicode.IsNumber(caractere) && caractere != '_' {
+			return ""
+		}
+		if unicode.IsLetter(caractere) {
+			temLetra = true
+		}
+	}
+
+	if !temLetra {
+		return ""
+	}
+	return nome
+}
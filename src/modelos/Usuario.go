@@ -2,7 +2,6 @@ package modelos
 
 import (
 	"api/src/seguranca"
-	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -12,12 +11,20 @@ import (
 
 // Usuario representa um usuário utilizando a rede social
 type Usuario struct {
-	ID       uint64    `json:"id,omitempty"`
-	Nome     string    `json:"nome,omitempty"`
-	Nick     string    `json:"nick,omitempty"`
-	Email    string    `json:"email,omitempty"`
-	Senha    string    `json:"senha,omitempty"`
-	CriadoEm time.Time `json:"CriadoEm,omitempty"`
+	ID             uint64    `json:"id,omitempty"`
+	Nome           string    `json:"nome,omitempty"`
+	Nick           string    `json:"nick,omitempty"`
+	Email          string    `json:"email,omitempty"`
+	Senha          string    `json:"senha,omitempty"`
+	Bio            *string   `json:"bio,omitempty"`
+	AvatarURL      *string   `json:"avatarUrl,omitempty"`
+	CapaURL        *string   `json:"capaUrl,omitempty"`
+	Localizacao    *string   `json:"localizacao,omitempty"`
+	Website        *string   `json:"website,omitempty"`
+	DataNascimento *string   `json:"dataNascimento,omitempty"`
+	Privada        bool      `json:"privada"`
+	Versao         uint64    `json:"-"`
+	CriadoEm       time.Time `json:"CriadoEm,omitempty"`
 }
 
 // Preparar vai chamar os métodos para validar e formatar o usuário recebido
@@ -33,41 +40,57 @@ func (usuario *Usuario) Preparar(etapa string) error {
 	return nil
 }
 
-func (u *Usuario) validar(etapa string) error {
-
-	if erro := u.validated(u.Nome, "nome", etapa); erro != nil {
+// PrepararPatch valida e formata o usuário depois de um merge patch, considerando
+// apenas os campos que foram alterados
+func (usuario *Usuario) PrepararPatch(campos []string) error {
+	if erro := apenasCampos(usuario.validar("edicao"), campos); erro != nil {
 		return erro
 	}
 
-	if erro := u.validated(u.Nick, "nick", etapa); erro != nil {
-		return erro
-	}
+	return usuario.formatar("edicao")
+}
 
-	if erro := u.validated(u.Email, "email", etapa); erro != nil {
-		return erro
+func (u *Usuario) validar(etapa string) error {
+	erros := ErroDeValidacao{}
+
+	u.validated(erros, u.Nome, "nome")
+	u.validated(erros, u.Nick, "nick")
+	u.validated(erros, u.Email, "email")
+
+	if strings.ContainsAny(strings.TrimSpace(u.Nick), "@ \t\n") {
+		erros.Adicionar("nick", "O nick não pode ter @ nem espaços")
 	}
 
 	if erro := checkmail.ValidateFormat(u.Email); erro != nil {
-		return errors.New("Email não é válido")
+		erros.Adicionar("email", "Email não é válido")
 	}
 
-	if erro := u.validated(u.Senha, "senha", etapa); erro != nil {
-		return erro
+	u.validarPerfil(erros)
+
+	if etapa == "cadastro" {
+		u.validated(erros, u.Senha, "senha")
 	}
-	return nil
+
+	if etapa == "cadastro" && u.Senha != "" {
+		for _, problema := range seguranca.ValidarSenha(u.Senha, u.Nick, u.Email) {
+			erros.Adicionar("senha", problema)
+		}
+	}
+
+	return erros.Erro()
 }
 
-func (u *Usuario) validated(text string, campo string, etapa string) error {
-	if etapa == "cadastro" && text == "" {
-		return errors.New(fmt.Sprintf("O %s não pode estar em branco", campo))
+func (u *Usuario) validated(erros ErroDeValidacao, text string, campo string) {
+	if strings.TrimSpace(text) == "" {
+		erros.Adicionar(campo, fmt.Sprintf("O %s não pode estar em branco", campo))
 	}
-	return nil
 }
 
 func (u *Usuario) formatar(etapa string) error {
 	u.Nome = strings.TrimSpace(u.Nome)
 	u.Nick = strings.TrimSpace(u.Nick)
 	u.Email = strings.TrimSpace(u.Email)
+	u.formatarPerfil()
 
 	if etapa == "cadastro" {
 		senhaComHash, erro := seguranca.Hash(u.Senha)
@@ -0,0 +1,48 @@
+package notificacoes
+
+import (
+	"api/src/eventos"
+	"api/src/modelos"
+	"api/src/repositorio"
+	"database/sql"
+	"log"
+)
+
+// Emitir registra uma notificação para o usuário, respeitando as preferências dele. Avisos
+// sobre as próprias ações são ignorados. Falhas vão só para o log, para não desfazer a ação
+// que gerou o aviso
+func Emitir(db *sql.DB, notificacao modelos.Notificacao) {
+	if notificacao.UsuarioID == notificacao.AtorID {
+		return
+	}
+
+	repositorio := repositorio.NovoRepositorioDeNotificacoes(db)
+
+	ativo, erro := repositorio.TipoAtivo(notificacao.UsuarioID, notificacao.Tipo)
+	if erro != nil {
+		log.Printf("Erro ao emitir notificação %s para o usuário %d: %v", notificacao.Tipo, notificacao.UsuarioID, erro)
+		return
+	}
+
+	if !ativo {
+		return
+	}
+
+	notificacaoID, mudou, erro := repositorio.Registrar(notificacao)
+	if erro != nil {
+		log.Printf("Erro ao emitir notificação %s para o usuário %d: %v", notificacao.Tipo, notificacao.UsuarioID, erro)
+		return
+	}
+
+	// Um ator repetido não muda a notificação agrupada e não precisa ser avisado de novo
+	if !mudou {
+		return
+	}
+
+	registrada, erro := repositorio.BuscarPorID(notificacaoID, notificacao.UsuarioID)
+	if erro != nil {
+		log.Printf("Erro ao publicar notificação %d: %v", notificacaoID, erro)
+		return
+	}
+	eventos.Publicar(eventos.TopicoNotificacoes(notificacao.UsuarioID), eventos.EventoNotificacao, registrada)
+}
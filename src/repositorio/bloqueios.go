@@ -0,0 +1,108 @@
+package repositorio
+
+import (
+	"api/src/modelos"
+	"database/sql"
+)
+
+type Bloqueios struct {
+	db *sql.DB
+}
+
+// NovoRepositorioDeBloqueios cria uma estancia nova de repositorio para bloqueios
+func NovoRepositorioDeBloqueios(db *sql.DB) *Bloqueios {
+	return &Bloqueios{db}
+}
+
+// semBloqueioCom filtra os registros cujo usuário na coluna informada não tem bloqueio, em
+// nenhuma direção, com o usuário passado como parâmetro duas vezes
+func semBloqueioCom(coluna string) string {
+	return `not exists (
+		select 1 from bloqueios b
+		where (b.usuario_id = ? and b.bloqueado_id = ` + coluna + `)
+		or (b.usuario_id = ` + coluna + ` and b.bloqueado_id = ?)
+	)`
+}
+
+// Bloquear bloqueia um usuário e desfaz os seguimentos e solicitações entre os dois.
+// Retorna ErrNaoEncontrado quando o usuário bloqueado não existe
+func (repositorio Bloqueios) Bloquear(usuarioID, bloqueadoID uint64) error {
+	transacao, erro := repositorio.db.Begin()
+	if erro != nil {
+		return erro
+	}
+	defer transacao.Rollback()
+
+	resultado, erro := transacao.Exec(`
+		insert ignore into bloqueios (usuario_id, bloqueado_id)
+		select ?, id from usuarios where id = ?
+	`, usuarioID, bloqueadoID)
+	if erro != nil {
+		return erro
+	}
+
+	linhas, erro := resultado.RowsAffected()
+	if erro != nil {
+		return erro
+	}
+
+	// Nenhuma linha inserida significa que o bloqueio já existia ou que o usuário não existe
+	if linhas == 0 {
+		var existe bool
+		if erro = transacao.QueryRow("select exists (select 1 from usuarios where id = ?)", bloqueadoID).Scan(&existe); erro != nil {
+			return erro
+		}
+		if !existe {
+			return ErrNaoEncontrado
+		}
+	}
+
+	if _, erro = transacao.Exec(`
+		delete from seguidores
+		where (usuario_id = ? and seguidor_id = ?) or (usuario_id = ? and seguidor_id = ?)
+	`, usuarioID, bloqueadoID, bloqueadoID, usuarioID); erro != nil {
+		return erro
+	}
+
+	return transacao.Commit()
+}
+
+// Desbloquear desfaz o bloqueio. Os seguimentos desfeitos pelo bloqueio não voltam
+func (repositorio Bloqueios) Desbloquear(usuarioID, bloqueadoID uint64) error {
+	resultado, erro := repositorio.db.Exec(
+		"delete from bloqueios where usuario_id = ? and bloqueado_id = ?", usuarioID, bloqueadoID,
+	)
+	if erro != nil {
+		return erro
+	}
+
+	return conferirAlteracao(resultado)
+}
+
+// Buscar traz os usuários bloqueados pelo usuário
+func (repositorio Bloqueios) Buscar(usuarioID uint64) ([]modelos.Usuario, error) {
+	linhas, erro := repositorio.db.Query(`
+		select u.id, u.nome, u.nick, u.email, u.criadoEm from usuarios u
+		inner join bloqueios b on b.bloqueado_id = u.id
+		where b.usuario_id = ?
+		order by b.criadoEm desc
+	`, usuarioID)
+	if erro != nil {
+		return nil, erro
+	}
+	defer linhas.Close()
+
+	return escanearUsuarios(linhas)
+}
+
+// ExisteEntre informa se um dos dois usuários bloqueou o outro
+func (repositorio Bloqueios) ExisteEntre(usuarioID, outroID uint64) (bool, error) {
+	var existe bool
+	erro := repositorio.db.QueryRow(`
+		select exists (
+			select 1 from bloqueios
+			where (usuario_id = ? and bloqueado_id = ?) or (usuario_id = ? and bloqueado_id = ?)
+		)
+	`, usuarioID, outroID, outroID, usuarioID).Scan(&existe)
+	return existe, erro
+}
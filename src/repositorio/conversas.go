@@ -0,0 +1,533 @@
+package repositorio
+
+import (
+	"api/src/modelos"
+	"database/sql"
+	"fmt"
+)
+
+type Conversas struct {
+	db *sql.DB
+}
+
+// NovoRepositorioDeConversas cria uma estancia nova de repositorio para conversas
+func NovoRepositorioDeConversas(db *sql.DB) *Conversas {
+	return &Conversas{db}
+}
+
+// PodeReceberMensagem informa se o remetente pode mandar mensagens ao destinatário. Contas
+// privadas só recebem de seguidores aprovados. Retorna ErrNaoEncontrado quando o destinatário
+// não existe ou há bloqueio entre os dois, como acontece com o perfil
+func (repositorio Conversas) PodeReceberMensagem(remetenteID, destinatarioID uint64) (bool, error) {
+	var privada, seguidor bool
+	erro := repositorio.db.QueryRow(`
+		select u.privada, exists (
+			select 1 from seguidores s
+			where s.usuario_id = u.id and s.seguidor_id = ? and not s.pendente
+		) from usuarios u
+		where u.id = ? and `+semBloqueioCom("u.id")+`
+	`, remetenteID, destinatarioID, remetenteID, remetenteID).Scan(&privada, &seguidor)
+	if erro == sql.ErrNoRows {
+		return false, ErrNaoEncontrado
+	}
+	if erro != nil {
+		return false, erro
+	}
+
+	return !privada || seguidor, nil
+}
+
+// BuscarOuCriarDireta retorna a conversa entre os dois usuários, criando-a quando ainda não
+// existe. Criada informa se a conversa é nova
+func (repositorio Conversas) BuscarOuCriarDireta(usuarioID, outroID uint64) (uint64, bool, error) {
+	chave := chaveDireta(usuarioID, outroID)
+
+	transacao, erro := repositorio.db.Begin()
+	if erro != nil {
+		return 0, false, erro
+	}
+	defer transacao.Rollback()
+
+	resultado, erro := transacao.Exec(
+		"insert ignore into conversas (tipo, chave) values (?, ?)", modelos.ConversaDireta, chave,
+	)
+	if erro != nil {
+		return 0, false, erro
+	}
+
+	linhas, erro := resultado.RowsAffected()
+	if erro != nil {
+		return 0, false, erro
+	}
+
+	var conversaID uint64
+	if erro = transacao.QueryRow("select id from conversas where chave = ?", chave).Scan(&conversaID); erro != nil {
+		return 0, false, erro
+	}
+
+	if _, erro = transacao.Exec(
+		"insert ignore into conversa_membros (conversa_id, usuario_id) values (?, ?), (?, ?)",
+		conversaID, usuarioID, conversaID, outroID,
+	); erro != nil {
+		return 0, false, erro
+	}
+
+	return conversaID, linhas > 0, transacao.Commit()
+}
+
+// ExisteDireta informa se os dois usuários já têm uma conversa direta
+func (repositorio Conversas) ExisteDireta(usuarioID, outroID uint64) (bool, error) {
+	var existe bool
+	erro := repositorio.db.QueryRow(
+		"select exists (select 1 from conversas where chave = ?)", chaveDireta(usuarioID, outroID),
+	).Scan(&existe)
+	return existe, erro
+}
+
+// chaveDireta identifica a conversa direta entre dois usuários. Ela não depende de quem começou
+// a conversa, então os dois sempre caem na mesma
+func chaveDireta(usuarioID, outroID uint64) string {
+	menor, maior := usuarioID, outroID
+	if menor > maior {
+		menor, maior = maior, menor
+	}
+	return fmt.Sprintf("direta:%d:%d", menor, maior)
+}
+
+// Buscar traz as conversas do usuário que já têm mensagens, da mais recente para a mais antiga
+func (repositorio Conversas) Buscar(usuarioID uint64) ([]modelos.Conversa, error) {
+	return repositorio.buscar("m.id is not null order by m.id desc", usuarioID)
+}
+
+// BuscarPorID traz uma conversa do usuário. Conversas de que ele não participa retornam
+// ErrNaoEncontrado
+func (repositorio Conversas) BuscarPorID(conversaID, usuarioID uint64) (modelos.Conversa, error) {
+	conversas, erro := repositorio.buscar("c.id = ?", usuarioID, conversaID)
+	if erro != nil {
+		return modelos.Conversa{}, erro
+	}
+
+	if len(conversas) == 0 {
+		return modelos.Conversa{}, ErrNaoEncontrado
+	}
+	return conversas[0], nil
+}
+
+// buscar traz as conversas do usuário com a última mensagem e o total de não lidas. O primeiro
+// argumento é o usuário, os demais são os do filtro
+func (repositorio Conversas) buscar(filtro string, argumentos ...interface{}) ([]modelos.Conversa, error) {
+	linhas, erro := repositorio.db.Query(`
+		select c.id, c.tipo, coalesce(c.nome, ''), c.criadaEm, (
+			select count(*) from mensagens n
+			where n.conversa_id = c.id and n.id > cm.ultimaLidaID and n.autor_id <> cm.usuario_id
+		), m.id, m.tipo, m.autor_id, u.nick, m.conteudo, m.criadaEm
+		from conversas c
+		inner join conversa_membros cm on cm.conversa_id = c.id
+		left join mensagens m on m.id = (
+			select max(id) from mensagens where conversa_id = c.id and id > cm.desdeID
+		)
+		left join usuarios u on u.id = m.autor_id
+		where cm.usuario_id = ? and `+filtro, argumentos...)
+	if erro != nil {
+		return nil, erro
+	}
+	defer linhas.Close()
+
+	var conversas []modelos.Conversa
+	for linhas.Next() {
+		var conversa modelos.Conversa
+		var mensagemID, autorID sql.NullInt64
+		var tipo, autorNick, conteudo sql.NullString
+		var enviadaEm sql.NullTime
+
+		if erro = linhas.Scan(
+			&conversa.ID,
+			&conversa.Tipo,
+			&conversa.Nome,
+			&conversa.CriadaEm,
+			&conversa.NaoLidas,
+			&mensagemID,
+			&tipo,
+			&autorID,
+			&autorNick,
+			&conteudo,
+			&enviadaEm,
+		); erro != nil {
+			return nil, erro
+		}
+
+		if mensagemID.Valid {
+			conversa.UltimaMensagem = &modelos.Mensagem{
+				ID:         uint64(mensagemID.Int64),
+				ConversaID: conversa.ID,
+				Tipo:       tipo.String,
+				AutorID:    uint64(autorID.Int64),
+				AutorNick:  autorNick.String,
+				Conteudo:   conteudo.String,
+				CriadaEm:   enviadaEm.Time,
+			}
+		}
+		conversas = append(conversas, conversa)
+	}
+
+	if erro = linhas.Err(); erro != nil {
+		return nil, erro
+	}
+
+	if erro = repositorio.carregarParticipantes(conversas); erro != nil {
+		return nil, erro
+	}
+
+	// A última mensagem está lida quando nenhum outro participante parou antes dela
+	for i, conversa := range conversas {
+		if conversa.UltimaMensagem == nil {
+			continue
+		}
+
+		lida := true
+		for _, participante := range conversa.Outros(conversa.UltimaMensagem.AutorID) {
+			if participante.UltimaLidaID < conversa.UltimaMensagem.ID {
+				lida = false
+			}
+		}
+		conversas[i].UltimaMensagem.Lida = lida
+	}
+	return conversas, nil
+}
+
+func (repositorio Conversas) carregarParticipantes(conversas []modelos.Conversa) error {
+	if len(conversas) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, 0, len(conversas))
+	indices := map[uint64]int{}
+	for i, conversa := range conversas {
+		ids = append(ids, conversa.ID)
+		indices[conversa.ID] = i
+	}
+
+	marcadores, argumentos := emLista(ids)
+	linhas, erro := repositorio.db.Query(`
+		select cm.conversa_id, u.id, u.nick, cm.admin, cm.ultimaLidaID from conversa_membros cm
+		inner join usuarios u on u.id = cm.usuario_id
+		where cm.conversa_id in (`+marcadores+`)
+		order by cm.conversa_id, cm.entrouEm, u.id
+	`, argumentos...)
+	if erro != nil {
+		return erro
+	}
+	defer linhas.Close()
+
+	for linhas.Next() {
+		var conversaID uint64
+		var participante modelos.Participante
+		if erro = linhas.Scan(&conversaID, &participante.ID, &participante.Nick, &participante.Admin, &participante.UltimaLidaID); erro != nil {
+			return erro
+		}
+
+		i := indices[conversaID]
+		conversas[i].Participantes = append(conversas[i].Participantes, participante)
+	}
+	return linhas.Err()
+}
+
+// CriarMensagem grava a mensagem na conversa. Quem envia já leu tudo até ela. Sem tipo a
+// mensagem é do usuário
+func (repositorio Conversas) CriarMensagem(mensagem modelos.Mensagem) (uint64, error) {
+	transacao, erro := repositorio.db.Begin()
+	if erro != nil {
+		return 0, erro
+	}
+	defer transacao.Rollback()
+
+	if mensagem.Tipo == "" {
+		mensagem.Tipo = modelos.MensagemDoUsuario
+	}
+
+	resultado, erro := transacao.Exec(
+		"insert into mensagens (conversa_id, tipo, autor_id, conteudo) values (?, ?, ?, ?)",
+		mensagem.ConversaID, mensagem.Tipo, mensagem.AutorID, mensagem.Conteudo,
+	)
+	if erro != nil {
+		return 0, erro
+	}
+
+	mensagemID, erro := resultado.LastInsertId()
+	if erro != nil {
+		return 0, erro
+	}
+
+	if _, erro = transacao.Exec(
+		"update conversa_membros set ultimaLidaID = ? where conversa_id = ? and usuario_id = ?",
+		mensagemID, mensagem.ConversaID, mensagem.AutorID,
+	); erro != nil {
+		return 0, erro
+	}
+
+	return uint64(mensagemID), transacao.Commit()
+}
+
+// colunasMensagem são as colunas lidas por escanearMensagens, na mesma ordem. A mensagem está
+// lida quando nenhum outro participante parou de ler antes dela
+const colunasMensagem = `m.id, m.conversa_id, m.tipo, m.autor_id, u.nick, m.conteudo, m.criadaEm, not exists (
+	select 1 from conversa_membros o
+	where o.conversa_id = m.conversa_id and o.usuario_id <> m.autor_id and o.ultimaLidaID < m.id
+)`
+
+// BuscarMensagens traz as mensagens da conversa que o usuário pode ver, das mais novas para
+// as mais antigas. Com antes diferente de zero traz só as anteriores a essa mensagem, para
+// paginar o histórico. Quem entrou num grupo depois só vê as mensagens a partir da entrada
+func (repositorio Conversas) BuscarMensagens(conversaID, usuarioID, antes uint64, limite int) ([]modelos.Mensagem, error) {
+	linhas, erro := repositorio.db.Query(`
+		select `+colunasMensagem+` from mensagens m
+		inner join usuarios u on u.id = m.autor_id
+		inner join conversa_membros cm on cm.conversa_id = m.conversa_id and cm.usuario_id = ?
+		where m.conversa_id = ? and m.id > cm.desdeID and (? = 0 or m.id < ?)
+		order by m.id desc
+		limit ?
+	`, usuarioID, conversaID, antes, antes, limite)
+	if erro != nil {
+		return nil, erro
+	}
+	defer linhas.Close()
+
+	return escanearMensagens(linhas)
+}
+
+// BuscarMensagem traz uma mensagem da conversa
+func (repositorio Conversas) BuscarMensagem(conversaID, mensagemID uint64) (modelos.Mensagem, error) {
+	linhas, erro := repositorio.db.Query(`
+		select `+colunasMensagem+` from mensagens m
+		inner join usuarios u on u.id = m.autor_id
+		where m.conversa_id = ? and m.id = ?
+	`, conversaID, mensagemID)
+	if erro != nil {
+		return modelos.Mensagem{}, erro
+	}
+	defer linhas.Close()
+
+	mensagens, erro := escanearMensagens(linhas)
+	if erro != nil {
+		return modelos.Mensagem{}, erro
+	}
+
+	if len(mensagens) == 0 {
+		return modelos.Mensagem{}, ErrNaoEncontrado
+	}
+	return mensagens[0], nil
+}
+
+// DeletarMensagem apaga uma mensagem do autor na conversa. Retorna ErrNaoEncontrado quando
+// a mensagem não existe, é de outro usuário ou é do sistema
+func (repositorio Conversas) DeletarMensagem(conversaID, mensagemID, autorID uint64) error {
+	resultado, erro := repositorio.db.Exec(
+		"delete from mensagens where id = ? and conversa_id = ? and autor_id = ? and tipo = ?",
+		mensagemID, conversaID, autorID, modelos.MensagemDoUsuario,
+	)
+	if erro != nil {
+		return erro
+	}
+	return conferirAlteracao(resultado)
+}
+
+// CriarGrupo cria um grupo com o criador como administrador e os participantes informados
+func (repositorio Conversas) CriarGrupo(grupo modelos.Grupo, criadorID uint64) (uint64, error) {
+	transacao, erro := repositorio.db.Begin()
+	if erro != nil {
+		return 0, erro
+	}
+	defer transacao.Rollback()
+
+	resultado, erro := transacao.Exec(
+		"insert into conversas (tipo, nome) values (?, ?)", modelos.ConversaGrupo, grupo.Nome,
+	)
+	if erro != nil {
+		return 0, erro
+	}
+
+	conversaID, erro := resultado.LastInsertId()
+	if erro != nil {
+		return 0, erro
+	}
+
+	if _, erro = transacao.Exec(
+		"insert into conversa_membros (conversa_id, usuario_id, admin) values (?, ?, true)", conversaID, criadorID,
+	); erro != nil {
+		return 0, erro
+	}
+
+	for _, participante := range grupo.Participantes {
+		if _, erro = transacao.Exec(
+			"insert into conversa_membros (conversa_id, usuario_id) values (?, ?)", conversaID, participante,
+		); erro != nil {
+			return 0, erro
+		}
+	}
+
+	return uint64(conversaID), transacao.Commit()
+}
+
+// AdicionarMembro coloca o usuário no grupo, com acesso só às mensagens enviadas daqui em
+// diante. Retorna falso quando ele já participava
+func (repositorio Conversas) AdicionarMembro(conversaID, usuarioID uint64) (bool, error) {
+	resultado, erro := repositorio.db.Exec(`
+		insert ignore into conversa_membros (conversa_id, usuario_id, desdeID, ultimaLidaID)
+		select ?, ?, coalesce(max(id), 0), coalesce(max(id), 0) from mensagens where conversa_id = ?
+	`, conversaID, usuarioID, conversaID)
+	if erro != nil {
+		return false, erro
+	}
+
+	linhas, erro := resultado.RowsAffected()
+	return linhas > 0, erro
+}
+
+// RemoverMembro tira o usuário do grupo, e com ele o acesso ao histórico. Se o grupo fica
+// sem administradores o membro mais antigo vira administrador e é retornado, e o grupo vazio
+// é apagado
+func (repositorio Conversas) RemoverMembro(conversaID, usuarioID uint64) (uint64, error) {
+	transacao, erro := repositorio.db.Begin()
+	if erro != nil {
+		return 0, erro
+	}
+	defer transacao.Rollback()
+
+	if erro = travarConversa(transacao, conversaID); erro != nil {
+		return 0, erro
+	}
+
+	resultado, erro := transacao.Exec(
+		"delete from conversa_membros where conversa_id = ? and usuario_id = ?", conversaID, usuarioID,
+	)
+	if erro != nil {
+		return 0, erro
+	}
+
+	if erro = conferirAlteracao(resultado); erro != nil {
+		return 0, erro
+	}
+
+	var membros, admins int
+	if erro = transacao.QueryRow(
+		"select count(*), coalesce(sum(admin), 0) from conversa_membros where conversa_id = ?", conversaID,
+	).Scan(&membros, &admins); erro != nil {
+		return 0, erro
+	}
+
+	var promovidoID uint64
+	switch {
+	case membros == 0:
+		_, erro = transacao.Exec("delete from conversas where id = ?", conversaID)
+	case admins == 0:
+		if erro = transacao.QueryRow(
+			"select usuario_id from conversa_membros where conversa_id = ? order by entrouEm, usuario_id limit 1", conversaID,
+		).Scan(&promovidoID); erro != nil {
+			return 0, erro
+		}
+		_, erro = transacao.Exec(
+			"update conversa_membros set admin = true where conversa_id = ? and usuario_id = ?", conversaID, promovidoID,
+		)
+	}
+	if erro != nil {
+		return 0, erro
+	}
+
+	return promovidoID, transacao.Commit()
+}
+
+// DefinirAdmin torna o membro administrador do grupo ou tira dele essa função. Retorna
+// ErrNaoEncontrado quando ele não participa do grupo e ErrUltimoAdmin ao tirar o único
+// administrador
+func (repositorio Conversas) DefinirAdmin(conversaID, usuarioID uint64, admin bool) error {
+	transacao, erro := repositorio.db.Begin()
+	if erro != nil {
+		return erro
+	}
+	defer transacao.Rollback()
+
+	if erro = travarConversa(transacao, conversaID); erro != nil {
+		return erro
+	}
+
+	var eraAdmin bool
+	var admins int
+	erro = transacao.QueryRow(`
+		select m.admin, (
+			select count(*) from conversa_membros a where a.conversa_id = m.conversa_id and a.admin
+		) from conversa_membros m
+		where m.conversa_id = ? and m.usuario_id = ?
+	`, conversaID, usuarioID).Scan(&eraAdmin, &admins)
+	if erro == sql.ErrNoRows {
+		return ErrNaoEncontrado
+	}
+	if erro != nil {
+		return erro
+	}
+
+	if eraAdmin && !admin && admins == 1 {
+		return ErrUltimoAdmin
+	}
+
+	if _, erro = transacao.Exec(
+		"update conversa_membros set admin = ? where conversa_id = ? and usuario_id = ?", admin, conversaID, usuarioID,
+	); erro != nil {
+		return erro
+	}
+
+	return transacao.Commit()
+}
+
+// travarConversa trava a conversa até o fim da transação, para as mudanças de membros e
+// administradores do mesmo grupo acontecerem uma de cada vez e enxergarem a anterior
+func travarConversa(transacao *sql.Tx, conversaID uint64) error {
+	var id uint64
+	erro := transacao.QueryRow("select id from conversas where id = ? for update", conversaID).Scan(&id)
+	if erro == sql.ErrNoRows {
+		return ErrNaoEncontrado
+	}
+	return erro
+}
+
+// MarcarComoLida marca como lidas todas as mensagens da conversa para o usuário e retorna
+// a última mensagem lida
+func (repositorio Conversas) MarcarComoLida(conversaID, usuarioID uint64) (uint64, error) {
+	if _, erro := repositorio.db.Exec(`
+		update conversa_membros set ultimaLidaID = greatest(ultimaLidaID, coalesce(
+			(select max(id) from mensagens where conversa_id = ?), 0
+		))
+		where conversa_id = ? and usuario_id = ?
+	`, conversaID, conversaID, usuarioID); erro != nil {
+		return 0, erro
+	}
+
+	var ultimaLidaID uint64
+	erro := repositorio.db.QueryRow(
+		"select ultimaLidaID from conversa_membros where conversa_id = ? and usuario_id = ?", conversaID, usuarioID,
+	).Scan(&ultimaLidaID)
+	if erro == sql.ErrNoRows {
+		return 0, ErrNaoEncontrado
+	}
+	return ultimaLidaID, erro
+}
+
+func escanearMensagens(linhas *sql.Rows) ([]modelos.Mensagem, error) {
+	var mensagens []modelos.Mensagem
+	for linhas.Next() {
+		var mensagem modelos.Mensagem
+		if erro := linhas.Scan(
+			&mensagem.ID,
+			&mensagem.ConversaID,
+			&mensagem.Tipo,
+			&mensagem.AutorID,
+			&mensagem.AutorNick,
+			&mensagem.Conteudo,
+			&mensagem.CriadaEm,
+			&mensagem.Lida,
+		); erro != nil {
+			return nil, erro
+		}
+		mensagens = append(mensagens, mensagem)
+	}
+	return mensagens, linhas.Err()
+}
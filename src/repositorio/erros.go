@@ -0,0 +1,45 @@
+package repositorio
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// ErrNaoEncontrado é retornado quando o registro não existe ou o usuário não pode vê-lo
+var ErrNaoEncontrado = errors.New("Registro não encontrado")
+
+// ErrVersaoDesatualizada é retornado quando o registro foi alterado depois da versão informada
+var ErrVersaoDesatualizada = errors.New("O registro foi alterado por outra requisição, busque a versão atual e tente novamente")
+
+// ErrUltimoAdmin é retornado ao tirar a função do único administrador de um grupo
+var ErrUltimoAdmin = errors.New("O grupo precisa de pelo menos um administrador")
+
+// conferirVersao traduz uma alteração condicionada à versão que não afetou nenhuma linha
+func conferirVersao(resultado sql.Result, versao uint64) error {
+	if versao == 0 {
+		return nil
+	}
+
+	linhas, erro := resultado.RowsAffected()
+	if erro != nil {
+		return erro
+	}
+
+	if linhas == 0 {
+		return ErrVersaoDesatualizada
+	}
+	return nil
+}
+
+// conferirAlteracao retorna ErrNaoEncontrado quando a alteração não afetou nenhuma linha
+func conferirAlteracao(resultado sql.Result) error {
+	linhas, erro := resultado.RowsAffected()
+	if erro != nil {
+		return erro
+	}
+
+	if linhas == 0 {
+		return ErrNaoEncontrado
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package repositorio
+
+import (
+	"database/sql"
+	"time"
+)
+
+type LinksMagicos struct {
+	db *sql.DB
+}
+
+// NovoRepositorioDeLinksMagicos cria uma estancia nova de repositorio para links de login
+func NovoRepositorioDeLinksMagicos(db *sql.DB) *LinksMagicos {
+	return &LinksMagicos{db}
+}
+
+// Criar salva o hash de um link de login, descartando os links ainda não usados do usuário
+func (repositorio LinksMagicos) Criar(tokenHash string, usuarioID uint64, expiraEm time.Time) error {
+	if _, erro := repositorio.db.Exec(
+		"delete from links_magicos where usuario_id = ? and usadoEm is null", usuarioID,
+	); erro != nil {
+		return erro
+	}
+
+	statement, erro := repositorio.db.Prepare(
+		"insert into links_magicos (token_hash, usuario_id, expiraEm) values (?, ?, ?)",
+	)
+	if erro != nil {
+		return erro
+	}
+
+	defer statement.Close()
+
+	if _, erro = statement.Exec(tokenHash, usuarioID, expiraEm); erro != nil {
+		return erro
+	}
+	return nil
+}
+
+// Consumir marca o link como usado e retorna o id do usuário. Links expirados, já usados
+// ou inexistentes retornam id zero
+func (repositorio LinksMagicos) Consumir(tokenHash string) (uint64, error) {
+	resultado, erro := repositorio.db.Exec(`
+		update links_magicos set usadoEm = now()
+		where token_hash = ? and usadoEm is null and expiraEm > now()
+	`, tokenHash)
+	if erro != nil {
+		return 0, erro
+	}
+
+	linhasAfetadas, erro := resultado.RowsAffected()
+	if erro != nil || linhasAfetadas == 0 {
+		return 0, erro
+	}
+
+	var usuarioID uint64
+	if erro = repositorio.db.QueryRow(
+		"select usuario_id from links_magicos where token_hash = ?", tokenHash,
+	).Scan(&usuarioID); erro != nil {
+		return 0, erro
+	}
+	return usuarioID, nil
+}
@@ -0,0 +1,138 @@
+package repositorio
+
+import (
+	"api/src/modelos"
+	"database/sql"
+)
+
+type Midias struct {
+	db *sql.DB
+}
+
+// NovoRepositorioDeMidias cria uma estancia nova de repositorio para midias
+func NovoRepositorioDeMidias(db *sql.DB) *Midias {
+	return &Midias{db}
+}
+
+// Criar insere uma mídia no banco de dados
+func (repositorio Midias) Criar(midia modelos.Midia) (uint64, error) {
+	statement, erro := repositorio.db.Prepare(`
+		insert into midias (usuario_id, hash, tipo, extensao, largura, altura, tamanho)
+		values (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if erro != nil {
+		return 0, erro
+	}
+
+	defer statement.Close()
+
+	resultado, erro := statement.Exec(
+		midia.UsuarioID, midia.Hash, midia.Tipo, midia.Extensao, midia.Largura, midia.Altura, midia.Tamanho,
+	)
+	if erro != nil {
+		return 0, erro
+	}
+
+	ultimoIdInserido, erro := resultado.LastInsertId()
+	if erro != nil {
+		return 0, erro
+	}
+
+	return uint64(ultimoIdInserido), nil
+}
+
+// BuscarPorID busca uma mídia, retornando id zero quando ela não existe
+func (repositorio Midias) BuscarPorID(midiaID uint64) (modelos.Midia, error) {
+	linha, erro := repositorio.db.Query(`
+		select id, usuario_id, hash, tipo, extensao, largura, altura, tamanho, criadaEm
+		from midias where id = ?
+	`, midiaID)
+	if erro != nil {
+		return modelos.Midia{}, erro
+	}
+
+	defer linha.Close()
+
+	var midia modelos.Midia
+
+	if linha.Next() {
+		if erro = linha.Scan(
+			&midia.ID,
+			&midia.UsuarioID,
+			&midia.Hash,
+			&midia.Tipo,
+			&midia.Extensao,
+			&midia.Largura,
+			&midia.Altura,
+			&midia.Tamanho,
+			&midia.CriadaEm,
+		); erro != nil {
+			return modelos.Midia{}, erro
+		}
+	}
+	return midia, nil
+}
+
+// PodeVer informa se o leitor pode ver a mídia: quem a enviou sempre pode, os demais só
+// quando ela está numa publicação que eles podem ver ou é o avatar do dono, sem bloqueio
+// entre os dois
+func (repositorio Midias) PodeVer(midiaID, leitorID uint64) (bool, error) {
+	argumentos := []interface{}{midiaID, leitorID, midiaID}
+	argumentos = append(argumentos, leitor(leitorID)...)
+	argumentos = append(argumentos, midiaID, leitorID, leitorID)
+
+	var pode bool
+	erro := repositorio.db.QueryRow(`
+		select exists (
+			select 1 from midias where id = ? and usuario_id = ?
+		) or exists (
+			select 1 from publicacao_midias pm
+			inner join publicacao p on p.id = pm.publicacao_id
+			inner join usuarios u on u.id = p.autor_id
+			where pm.midia_id = ? and `+publicacaoVisivel+`
+		) or exists (
+			select 1 from midias m
+			inner join usuarios u on u.id = m.usuario_id
+			where m.id = ? and u.avatar_url like concat('%/midias/', m.id, '/%')
+			and `+semBloqueioCom("u.id")+`
+		)
+	`, argumentos...).Scan(&pode)
+	return pode, erro
+}
+
+// PertencemAoUsuario verifica se todas as mídias informadas foram enviadas pelo usuário
+func (repositorio Midias) PertencemAoUsuario(midias []uint64, usuarioID uint64) (bool, error) {
+	for _, midiaID := range midias {
+		var quantidade int
+		if erro := repositorio.db.QueryRow(
+			"select count(*) from midias where id = ? and usuario_id = ?", midiaID, usuarioID,
+		).Scan(&quantidade); erro != nil {
+			return false, erro
+		}
+
+		if quantidade == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Deletar remove uma mídia e informa se o arquivo ainda é usado por outra mídia com o mesmo conteúdo
+func (repositorio Midias) Deletar(midia modelos.Midia) (bool, error) {
+	statement, erro := repositorio.db.Prepare("delete from midias where id = ?")
+	if erro != nil {
+		return false, erro
+	}
+
+	defer statement.Close()
+
+	if _, erro = statement.Exec(midia.ID); erro != nil {
+		return false, erro
+	}
+
+	var quantidade int
+	if erro = repositorio.db.QueryRow("select count(*) from midias where hash = ?", midia.Hash).Scan(&quantidade); erro != nil {
+		return false, erro
+	}
+	return quantidade > 0, nil
+}
@@ -0,0 +1,246 @@
+package repositorio
+
+import (
+	"api/src/modelos"
+	"database/sql"
+)
+
+type Notificacoes struct {
+	db *sql.DB
+}
+
+// NovoRepositorioDeNotificacoes cria uma estancia nova de repositorio para notificacoes
+func NovoRepositorioDeNotificacoes(db *sql.DB) *Notificacoes {
+	return &Notificacoes{db}
+}
+
+// janelaDeAgrupamento é por quanto tempo uma notificação não lida continua recebendo novos atores
+const janelaDeAgrupamento = "interval 1 day"
+
+// Registrar grava a notificação, agrupando com uma não lida do mesmo tipo e publicação quando
+// existe. Cada ator conta uma vez por notificação, então curtir e descurtir várias vezes não
+// infla o total. Retorna a notificação registrada e se ela mudou
+func (repositorio Notificacoes) Registrar(notificacao modelos.Notificacao) (uint64, bool, error) {
+	transacao, erro := repositorio.db.Begin()
+	if erro != nil {
+		return 0, false, erro
+	}
+	defer transacao.Rollback()
+
+	var notificacaoID uint64
+	erro = transacao.QueryRow(`
+		select id from notificacoes
+		where usuario_id = ? and tipo = ? and publicacao_id <=> ? and not lida
+		and atualizadaEm > now() - `+janelaDeAgrupamento+`
+		order by id desc limit 1
+		for update
+	`, notificacao.UsuarioID, notificacao.Tipo, notificacao.PublicacaoID).Scan(&notificacaoID)
+
+	switch {
+	case erro == sql.ErrNoRows:
+		resultado, erro := transacao.Exec(
+			"insert into notificacoes (usuario_id, tipo, publicacao_id, ator_id) values (?, ?, ?, ?)",
+			notificacao.UsuarioID, notificacao.Tipo, notificacao.PublicacaoID, notificacao.AtorID,
+		)
+		if erro != nil {
+			return 0, false, erro
+		}
+
+		id, erro := resultado.LastInsertId()
+		if erro != nil {
+			return 0, false, erro
+		}
+		notificacaoID = uint64(id)
+
+	case erro != nil:
+		return 0, false, erro
+	}
+
+	resultado, erro := transacao.Exec(
+		"insert ignore into notificacao_atores (notificacao_id, ator_id) values (?, ?)", notificacaoID, notificacao.AtorID,
+	)
+	if erro != nil {
+		return 0, false, erro
+	}
+
+	novoAtor, erro := resultado.RowsAffected()
+	if erro != nil {
+		return 0, false, erro
+	}
+
+	if novoAtor == 0 {
+		return notificacaoID, false, transacao.Commit()
+	}
+
+	if _, erro = transacao.Exec(`
+		update notificacoes set ator_id = ?, atualizadaEm = now(),
+		atores = (select count(*) from notificacao_atores where notificacao_id = ?)
+		where id = ?
+	`, notificacao.AtorID, notificacaoID, notificacaoID); erro != nil {
+		return 0, false, erro
+	}
+
+	return notificacaoID, true, transacao.Commit()
+}
+
+// colunasNotificacao são as colunas lidas por escanearNotificacoes, na mesma ordem
+const colunasNotificacao = "n.id, n.usuario_id, n.tipo, n.publicacao_id, n.ator_id, u.nick, n.atores, n.lida, n.criadaEm, n.atualizadaEm"
+
+// Buscar traz as notificações do usuário, das mais recentes para as mais antigas
+func (repositorio Notificacoes) Buscar(usuarioID uint64, apenasNaoLidas bool, limite int) ([]modelos.Notificacao, error) {
+	linhas, erro := repositorio.db.Query(`
+		select `+colunasNotificacao+` from notificacoes n
+		inner join usuarios u on u.id = n.ator_id
+		where n.usuario_id = ? and (? = false or not n.lida)
+		order by n.atualizadaEm desc, n.id desc
+		limit ?
+	`, usuarioID, apenasNaoLidas, limite)
+	if erro != nil {
+		return nil, erro
+	}
+	defer linhas.Close()
+
+	return escanearNotificacoes(linhas)
+}
+
+// BuscarPorID traz uma notificação do usuário
+func (repositorio Notificacoes) BuscarPorID(notificacaoID, usuarioID uint64) (modelos.Notificacao, error) {
+	linhas, erro := repositorio.db.Query(`
+		select `+colunasNotificacao+` from notificacoes n
+		inner join usuarios u on u.id = n.ator_id
+		where n.id = ? and n.usuario_id = ?
+	`, notificacaoID, usuarioID)
+	if erro != nil {
+		return modelos.Notificacao{}, erro
+	}
+	defer linhas.Close()
+
+	notificacoes, erro := escanearNotificacoes(linhas)
+	if erro != nil {
+		return modelos.Notificacao{}, erro
+	}
+
+	if len(notificacoes) == 0 {
+		return modelos.Notificacao{}, ErrNaoEncontrado
+	}
+	return notificacoes[0], nil
+}
+
+// ContarNaoLidas conta as notificações não lidas do usuário
+func (repositorio Notificacoes) ContarNaoLidas(usuarioID uint64) (uint64, error) {
+	var total uint64
+	erro := repositorio.db.QueryRow(
+		"select count(*) from notificacoes where usuario_id = ? and not lida", usuarioID,
+	).Scan(&total)
+	return total, erro
+}
+
+// MarcarComoLida marca uma notificação do usuário como lida
+func (repositorio Notificacoes) MarcarComoLida(notificacaoID, usuarioID uint64) error {
+	resultado, erro := repositorio.db.Exec(
+		"update notificacoes set lida = true where id = ? and usuario_id = ?", notificacaoID, usuarioID,
+	)
+	if erro != nil {
+		return erro
+	}
+
+	linhas, erro := resultado.RowsAffected()
+	if erro != nil {
+		return erro
+	}
+
+	// Marcar de novo uma notificação já lida não altera linhas, mas não é um erro
+	if linhas == 0 {
+		_, erro = repositorio.BuscarPorID(notificacaoID, usuarioID)
+	}
+	return erro
+}
+
+// MarcarTodasComoLidas marca todas as notificações do usuário como lidas
+func (repositorio Notificacoes) MarcarTodasComoLidas(usuarioID uint64) error {
+	_, erro := repositorio.db.Exec("update notificacoes set lida = true where usuario_id = ? and not lida", usuarioID)
+	return erro
+}
+
+// BuscarPreferencias traz, para cada tipo, se o usuário quer receber as notificações. Tipos
+// sem preferência salva vêm ligados
+func (repositorio Notificacoes) BuscarPreferencias(usuarioID uint64) (map[string]bool, error) {
+	preferencias := map[string]bool{}
+	for _, tipo := range modelos.TiposDeNotificacao {
+		preferencias[tipo] = true
+	}
+
+	linhas, erro := repositorio.db.Query(
+		"select tipo, ativa from preferencias_notificacao where usuario_id = ?", usuarioID,
+	)
+	if erro != nil {
+		return nil, erro
+	}
+	defer linhas.Close()
+
+	for linhas.Next() {
+		var tipo string
+		var ativa bool
+		if erro = linhas.Scan(&tipo, &ativa); erro != nil {
+			return nil, erro
+		}
+		preferencias[tipo] = ativa
+	}
+	return preferencias, nil
+}
+
+// SalvarPreferencias grava as preferências informadas, mantendo as demais
+func (repositorio Notificacoes) SalvarPreferencias(usuarioID uint64, preferencias map[string]bool) error {
+	for tipo, ativa := range preferencias {
+		if _, erro := repositorio.db.Exec(`
+			insert into preferencias_notificacao (usuario_id, tipo, ativa) values (?, ?, ?)
+			on duplicate key update ativa = values(ativa)
+		`, usuarioID, tipo, ativa); erro != nil {
+			return erro
+		}
+	}
+	return nil
+}
+
+// TipoAtivo informa se o usuário quer receber notificações do tipo
+func (repositorio Notificacoes) TipoAtivo(usuarioID uint64, tipo string) (bool, error) {
+	var desligado bool
+	erro := repositorio.db.QueryRow(
+		"select exists (select 1 from preferencias_notificacao where usuario_id = ? and tipo = ? and not ativa)",
+		usuarioID, tipo,
+	).Scan(&desligado)
+	return !desligado, erro
+}
+
+func escanearNotificacoes(linhas *sql.Rows) ([]modelos.Notificacao, error) {
+	var notificacoes []modelos.Notificacao
+
+	for linhas.Next() {
+		var notificacao modelos.Notificacao
+		var publicacaoID sql.NullInt64
+
+		if erro := linhas.Scan(
+			&notificacao.ID,
+			&notificacao.UsuarioID,
+			&notificacao.Tipo,
+			&publicacaoID,
+			&notificacao.AtorID,
+			&notificacao.AtorNick,
+			&notificacao.Atores,
+			&notificacao.Lida,
+			&notificacao.CriadaEm,
+			&notificacao.AtualizadaEm,
+		); erro != nil {
+			return nil, erro
+		}
+
+		if publicacaoID.Valid {
+			id := uint64(publicacaoID.Int64)
+			notificacao.PublicacaoID = &id
+		}
+
+		notificacao.Descrever()
+		notificacoes = append(notificacoes, notificacao)
+	}
+	return notificacoes, nil
+}
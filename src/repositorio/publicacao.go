@@ -3,8 +3,43 @@ package repositorio
 import (
 	"api/src/modelos"
 	"database/sql"
+	"fmt"
+	"strings"
 )
 
+// colunasPublicacao são as colunas lidas por escanearPublicacao, na mesma ordem
+const colunasPublicacao = `p.id, p.titulo, p.conteudo, p.autor_id, p.curtidas,
+	(select count(*) from repostagens r where r.publicacao_id = p.id),
+	p.visibilidade, p.citacao_id, p.versao, p.criadaEm, u.nick`
+
+// publicacaoVisivel filtra as publicações que o leitor pode ver: o autor vê tudo, seguidores
+// aprovados veem as públicas e as de seguidores e os demais só veem as públicas de contas
+// que não são privadas. Bloqueios em qualquer direção escondem tudo. Os parâmetros vêm de leitor
+var publicacaoVisivel = `(
+	` + semBloqueioCom("p.autor_id") + `
+	and (
+		p.autor_id = ?
+		or (p.visibilidade = 'publica' and not u.privada)
+		or (p.visibilidade in ('publica', 'seguidores') and exists (
+			select 1 from seguidores sv where sv.usuario_id = p.autor_id and sv.seguidor_id = ? and not sv.pendente
+		))
+	)
+)`
+
+// leitor repete o id do leitor para cada parâmetro de publicacaoVisivel
+func leitor(leitorID uint64) []interface{} {
+	return []interface{}{leitorID, leitorID, leitorID, leitorID}
+}
+
+// executor é atendido tanto pelo banco quanto por uma transação, para as mesmas consultas
+// servirem aos dois
+type executor interface {
+	Exec(query string, argumentos ...interface{}) (sql.Result, error)
+	Query(query string, argumentos ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, argumentos ...interface{}) *sql.Row
+	Prepare(query string) (*sql.Stmt, error)
+}
+
 type Publicacoes struct {
 	db *sql.DB
 }
@@ -14,9 +49,44 @@ func NovoRepositoriosDePublicacoes(db *sql.DB) *Publicacoes {
 	return &Publicacoes{db}
 }
 
+// CriarComVinculos insere a publicação com as mídias, as tags e as menções numa transação,
+// para uma falha no meio não deixar a publicação pela metade. Retorna o id e, como
+// VincularMencoes, as menções resolvidas e os usuários mencionados
+func (repositorio Publicacoes) CriarComVinculos(publicacao modelos.Publicacao) (uint64, []modelos.Mencao, []uint64, error) {
+	transacao, erro := repositorio.db.Begin()
+	if erro != nil {
+		return 0, nil, nil, erro
+	}
+	defer transacao.Rollback()
+
+	publicacaoID, erro := criarPublicacao(transacao, publicacao)
+	if erro != nil {
+		return 0, nil, nil, erro
+	}
+
+	if erro = vincularMidias(transacao, publicacaoID, publicacao.Midias); erro != nil {
+		return 0, nil, nil, erro
+	}
+
+	if erro = vincularTags(transacao, publicacaoID, publicacao.Tags); erro != nil {
+		return 0, nil, nil, erro
+	}
+
+	mencoes, novos, erro := vincularMencoes(transacao, publicacaoID, publicacao.AutorID, publicacao.ExtrairMencoes())
+	if erro != nil {
+		return 0, nil, nil, erro
+	}
+
+	return publicacaoID, mencoes, novos, transacao.Commit()
+}
+
 // Criar insere uma publicação no banco de dados
 func (repositorio Publicacoes) Criar(publicacao modelos.Publicacao) (uint64, error) {
-	statement, erro := repositorio.db.Prepare("insert into publicacao (titulo,conteudo, autor_id) values(?,?,?)")
+	return criarPublicacao(repositorio.db, publicacao)
+}
+
+func criarPublicacao(consultas executor, publicacao modelos.Publicacao) (uint64, error) {
+	statement, erro := consultas.Prepare("insert into publicacao (titulo,conteudo, autor_id, visibilidade, citacao_id) values(?,?,?,?,?)")
 
 	if erro != nil {
 		return 0, erro
@@ -24,7 +94,7 @@ func (repositorio Publicacoes) Criar(publicacao modelos.Publicacao) (uint64, err
 
 	defer statement.Close()
 
-	resultado, erro := statement.Exec(publicacao.Titulo, publicacao.Conteudo, publicacao.AutorID)
+	resultado, erro := statement.Exec(publicacao.Titulo, publicacao.Conteudo, publicacao.AutorID, publicacao.Visibilidade, publicacao.CitacaoID)
 
 	if erro != nil {
 		return 0, erro
@@ -39,13 +109,15 @@ func (repositorio Publicacoes) Criar(publicacao modelos.Publicacao) (uint64, err
 	return uint64(ultimoIdInserido), nil
 }
 
-// BuscarPorID busca uma unica publicacao do banco
-func (repositorio Publicacoes) BuscarPorID(publicacaoID uint64) (modelos.Publicacao, error) {
+// BuscarPorID busca uma unica publicacao do banco que o leitor pode ver. Publicações que
+// não existem e as que o leitor não pode ver retornam ErrNaoEncontrado
+func (repositorio Publicacoes) BuscarPorID(publicacaoID, leitorID uint64) (modelos.Publicacao, error) {
 	linha, erro := repositorio.db.Query(`
-			select p.*, u.nick from publicacao p
+			select `+colunasPublicacao+` from publicacao p
 			inner join usuarios u on u.id = p.autor_id
-			where p.id = ?
-		`, publicacaoID)
+			where p.id = ? and `+publicacaoVisivel,
+		append([]interface{}{publicacaoID}, leitor(leitorID)...)...,
+	)
 
 	if erro != nil {
 		return modelos.Publicacao{}, erro
@@ -53,101 +125,160 @@ func (repositorio Publicacoes) BuscarPorID(publicacaoID uint64) (modelos.Publica
 
 	defer linha.Close()
 
-	var publicacao modelos.Publicacao
+	if !linha.Next() {
+		return modelos.Publicacao{}, ErrNaoEncontrado
+	}
 
-	if linha.Next() {
-		if erro = linha.Scan(
-			&publicacao.ID,
-			&publicacao.Titulo,
-			&publicacao.Conteudo,
-			&publicacao.AutorID,
-			&publicacao.Curtidas,
-			&publicacao.CriadaEm,
-			&publicacao.AutorNick,
-		); erro != nil {
-			return modelos.Publicacao{}, erro
-		}
+	var publicacao modelos.Publicacao
+	if erro = escanearPublicacao(linha, &publicacao); erro != nil {
+		return modelos.Publicacao{}, erro
 	}
 
-	return publicacao, nil
+	publicacoes := []modelos.Publicacao{publicacao}
+	if erro = repositorio.completar(publicacoes, leitorID); erro != nil {
+		return modelos.Publicacao{}, erro
+	}
 
+	return publicacoes[0], nil
 }
 
-// Buscar retorna as publicações do usuario logado e dos que ele segue
+// Buscar retorna o feed do usuario logado: as publicações dele, dos que ele segue e das tags
+// que ele segue, mais as repostadas por quem ele segue. Cada publicação aparece uma vez, no momento do evento mais recente,
+// atribuída a quem repostou quando esse evento é uma repostagem. As silenciadas ficam de fora
 func (repositorio Publicacoes) Buscar(usuarioId uint64) ([]modelos.Publicacao, error) {
 	linhas, erro := repositorio.db.Query(`
-					select distinct p.*, u.nick from publicacao p
-					inner join usuarios u on u.id = p.autor_id
-					inner join seguidores s on p.autor_id = s.usuario_id 
-					where u.id = ? or s.seguidor_id = ?
-					order by 1 desc 
-					`, usuarioId, usuarioId)
-
+		select e.publicacao_id, e.repostada_por, coalesce(ru.nick, '') from (
+			select p.id as publicacao_id, 0 as repostada_por, p.criadaEm as momento from publicacao p
+			where p.autor_id = ? or exists (
+				select 1 from seguidores s where s.usuario_id = p.autor_id and s.seguidor_id = ? and not s.pendente
+			)
+			union all
+			select pt.publicacao_id, 0, p.criadaEm from publicacao_tags pt
+			inner join tags_seguidas ts on ts.tag_id = pt.tag_id
+			inner join publicacao p on p.id = pt.publicacao_id
+			where ts.usuario_id = ?
+			union all
+			select r.publicacao_id, r.usuario_id, r.criadaEm from repostagens r
+			where (r.usuario_id = ? or exists (
+				select 1 from seguidores s where s.usuario_id = r.usuario_id and s.seguidor_id = ? and not s.pendente
+			)) and not exists (
+				select 1 from usuarios_silenciados si
+				where si.usuario_id = ? and si.silenciado_id = r.usuario_id
+				and (si.expiraEm is null or si.expiraEm > now())
+			)
+		) e
+		left join usuarios ru on ru.id = e.repostada_por
+		order by e.momento desc, e.publicacao_id desc
+	`, usuarioId, usuarioId, usuarioId, usuarioId, usuarioId, usuarioId)
 	if erro != nil {
-		return []modelos.Publicacao{}, erro
+		return nil, erro
 	}
 
 	defer linhas.Close()
 
-	var publicacoes []modelos.Publicacao
+	// Só o evento mais recente de cada publicação entra no feed
+	var ordem []modelos.Publicacao
+	vistas := map[uint64]bool{}
 
 	for linhas.Next() {
-		var publicacao modelos.Publicacao
-		if erro = linhas.Scan(
-			&publicacao.ID,
-			&publicacao.Titulo,
-			&publicacao.Conteudo,
-			&publicacao.AutorID,
-			&publicacao.Curtidas,
-			&publicacao.CriadaEm,
-			&publicacao.AutorNick,
-		); erro != nil {
+		var evento modelos.Publicacao
+		if erro = linhas.Scan(&evento.ID, &evento.RepostadaPorID, &evento.RepostadaPorNick); erro != nil {
 			return nil, erro
 		}
-		publicacoes = append(publicacoes, publicacao)
+
+		if !vistas[evento.ID] {
+			vistas[evento.ID] = true
+			ordem = append(ordem, evento)
+		}
 	}
-	return publicacoes, nil
-}
 
-// Atualizar retorna as publicações do usuario logado e dos que ele segue
-func (repositorio Publicacoes) Atualizar(publicacaoId uint64, publicacao modelos.Publicacao) error {
-	statement, erro := repositorio.db.Prepare("update publicacao set titulo =?, conteudo =? where id = ?")
+	if erro = linhas.Err(); erro != nil {
+		return nil, erro
+	}
+
+	if len(ordem) == 0 {
+		return nil, nil
+	}
 
+	ids := make([]uint64, 0, len(ordem))
+	for _, evento := range ordem {
+		ids = append(ids, evento.ID)
+	}
+	marcadores, argumentos := emLista(ids)
+
+	encontradas, erro := repositorio.db.Query(`
+		select `+colunasPublicacao+` from publicacao p
+		inner join usuarios u on u.id = p.autor_id
+		where p.id in (`+marcadores+`) and `+publicacaoVisivel+` and `+foraDosSilenciados,
+		append(append(argumentos, leitor(usuarioId)...), usuarioId, usuarioId, usuarioId)...,
+	)
 	if erro != nil {
-		return erro
+		return nil, erro
 	}
 
-	defer statement.Close()
+	defer encontradas.Close()
 
-	if _, erro = statement.Exec(publicacao.Titulo, publicacao.Conteudo, publicacaoId); erro != nil {
-		return erro
+	porID := map[uint64]modelos.Publicacao{}
+	for encontradas.Next() {
+		var publicacao modelos.Publicacao
+		if erro = escanearPublicacao(encontradas, &publicacao); erro != nil {
+			return nil, erro
+		}
+		porID[publicacao.ID] = publicacao
 	}
 
-	return nil
+	var publicacoes []modelos.Publicacao
+	for _, evento := range ordem {
+		publicacao, visivel := porID[evento.ID]
+		if !visivel {
+			continue
+		}
+
+		publicacao.RepostadaPorID = evento.RepostadaPorID
+		publicacao.RepostadaPorNick = evento.RepostadaPorNick
+		publicacoes = append(publicacoes, publicacao)
+	}
+
+	if erro = repositorio.completar(publicacoes, usuarioId); erro != nil {
+		return nil, erro
+	}
+	return publicacoes, nil
 }
 
-// Deletar exclui uma publicação no banco
-func (repositorio Publicacoes) Deletar(publicacaoId uint64) error {
-	statement, erro := repositorio.db.Prepare("delete from publicacao where id=?")
+// Atualizar altera o título, o conteúdo e a visibilidade da publicação e incrementa a sua versão. Com uma versão
+// diferente de zero a alteração só acontece se a publicação ainda estiver nela
+func (repositorio Publicacoes) Atualizar(publicacaoId uint64, publicacao modelos.Publicacao, versao uint64) error {
+	resultado, erro := repositorio.db.Exec(
+		"update publicacao set titulo = ?, conteudo = ?, visibilidade = ?, versao = versao + 1 where id = ? and (? = 0 or versao = ?)",
+		publicacao.Titulo, publicacao.Conteudo, publicacao.Visibilidade, publicacaoId, versao, versao,
+	)
 	if erro != nil {
 		return erro
 	}
 
-	defer statement.Close()
+	return conferirVersao(resultado, versao)
+}
 
-	if _, erro = statement.Exec(publicacaoId); erro != nil {
+// Deletar exclui uma publicação no banco, respeitando a versão da mesma forma que Atualizar
+func (repositorio Publicacoes) Deletar(publicacaoId uint64, versao uint64) error {
+	resultado, erro := repositorio.db.Exec(
+		"delete from publicacao where id = ? and (? = 0 or versao = ?)", publicacaoId, versao, versao,
+	)
+	if erro != nil {
 		return erro
 	}
-	return nil
+
+	return conferirVersao(resultado, versao)
 }
 
-// BuscarPorUsuario traz uma publicação por usuario
-func (repositorio Publicacoes) BuscarPorUsuario(usuarioId uint64) ([]modelos.Publicacao, error) {
+// BuscarPorUsuario traz as publicações de um usuario que o leitor pode ver
+func (repositorio Publicacoes) BuscarPorUsuario(usuarioId, leitorID uint64) ([]modelos.Publicacao, error) {
 	linhas, erro := repositorio.db.Query(`
-		select p.*, u.nick from publicacao p 
+		select `+colunasPublicacao+` from publicacao p
 		join usuarios u on u.id = p.autor_id
-		where p.autor_id = ?
-	`, usuarioId)
+		where p.autor_id = ? and `+publicacaoVisivel+`
+		order by p.id desc
+	`, append([]interface{}{usuarioId}, leitor(leitorID)...)...)
 
 	if erro != nil {
 		return nil, erro
@@ -157,19 +288,15 @@ func (repositorio Publicacoes) BuscarPorUsuario(usuarioId uint64) ([]modelos.Pub
 
 	for linhas.Next() {
 		var publicacao modelos.Publicacao
-		if erro = linhas.Scan(
-			&publicacao.ID,
-			&publicacao.Titulo,
-			&publicacao.Conteudo,
-			&publicacao.AutorID,
-			&publicacao.Curtidas,
-			&publicacao.CriadaEm,
-			&publicacao.AutorNick,
-		); erro != nil {
+		if erro = escanearPublicacao(linhas, &publicacao); erro != nil {
 			return nil, erro
 		}
 		publicacoes = append(publicacoes, publicacao)
 	}
+
+	if erro = repositorio.completar(publicacoes, leitorID); erro != nil {
+		return nil, erro
+	}
 	return publicacoes, nil
 }
 
@@ -210,3 +337,473 @@ func (repositorio Publicacoes) DesCurtir(publicacaoID uint64) error {
 
 	return nil
 }
+
+// BuscarCurtidas retorna o total de curtidas da publicação
+func (repositorio Publicacoes) BuscarCurtidas(publicacaoID uint64) (uint64, error) {
+	var curtidas uint64
+	erro := repositorio.db.QueryRow("select curtidas from publicacao where id = ?", publicacaoID).Scan(&curtidas)
+	if erro == sql.ErrNoRows {
+		return 0, ErrNaoEncontrado
+	}
+	return curtidas, erro
+}
+
+// BuscarLeitoresNoFeed traz os seguidores do autor que veem a publicação no feed, deixando de
+// fora quem silenciou o autor ou algum termo dela
+func (repositorio Publicacoes) BuscarLeitoresNoFeed(publicacaoID uint64) ([]uint64, error) {
+	linhas, erro := repositorio.db.Query(`
+		select s.seguidor_id from publicacao p
+		inner join seguidores s on s.usuario_id = p.autor_id and not s.pendente
+		where p.id = ? and p.visibilidade <> 'somente_eu'
+		and not exists (
+			select 1 from usuarios_silenciados si
+			where si.usuario_id = s.seguidor_id and si.silenciado_id = p.autor_id
+			and (si.expiraEm is null or si.expiraEm > now())
+		) and not exists (
+			select 1 from palavras_silenciadas ps
+			where ps.usuario_id = s.seguidor_id
+			and (ps.expiraEm is null or ps.expiraEm > now())
+			and (locate(ps.termo, lower(p.titulo)) > 0 or locate(ps.termo, lower(p.conteudo)) > 0)
+		)
+	`, publicacaoID)
+	if erro != nil {
+		return nil, erro
+	}
+	defer linhas.Close()
+
+	var leitores []uint64
+	for linhas.Next() {
+		var leitorID uint64
+		if erro = linhas.Scan(&leitorID); erro != nil {
+			return nil, erro
+		}
+		leitores = append(leitores, leitorID)
+	}
+	return leitores, nil
+}
+
+// VincularTags troca as tags da publicação, criando as que ainda não existem
+func (repositorio Publicacoes) VincularTags(publicacaoID uint64, tags []string) error {
+	return vincularTags(repositorio.db, publicacaoID, tags)
+}
+
+func vincularTags(consultas executor, publicacaoID uint64, tags []string) error {
+	if _, erro := consultas.Exec("delete from publicacao_tags where publicacao_id = ?", publicacaoID); erro != nil {
+		return erro
+	}
+
+	for posicao, tag := range tags {
+		if _, erro := consultas.Exec("insert ignore into tags (nome) values (?)", tag); erro != nil {
+			return erro
+		}
+
+		if _, erro := consultas.Exec(`
+			insert ignore into publicacao_tags (publicacao_id, tag_id, posicao)
+			select ?, id, ? from tags where nome = ?
+		`, publicacaoID, posicao, tag); erro != nil {
+			return erro
+		}
+	}
+	return nil
+}
+
+// VincularMencoes resolve os nicks mencionados e troca as menções da publicação. Nicks que
+// não existem ou de usuários com bloqueio com o autor são ignorados. Retorna as menções
+// resolvidas e os usuários que ainda não estavam mencionados, sem o próprio autor
+func (repositorio Publicacoes) VincularMencoes(
+	publicacaoID, autorID uint64, mencoes []modelos.Mencao,
+) ([]modelos.Mencao, []uint64, error) {
+	return vincularMencoes(repositorio.db, publicacaoID, autorID, mencoes)
+}
+
+func vincularMencoes(
+	consultas executor, publicacaoID, autorID uint64, mencoes []modelos.Mencao,
+) ([]modelos.Mencao, []uint64, error) {
+	anteriores := map[uint64]bool{}
+
+	linhas, erro := consultas.Query("select usuario_id from mencoes where publicacao_id = ?", publicacaoID)
+	if erro != nil {
+		return nil, nil, erro
+	}
+	for linhas.Next() {
+		var usuarioID uint64
+		if erro = linhas.Scan(&usuarioID); erro != nil {
+			linhas.Close()
+			return nil, nil, erro
+		}
+		anteriores[usuarioID] = true
+	}
+	linhas.Close()
+
+	if _, erro = consultas.Exec("delete from mencoes where publicacao_id = ?", publicacaoID); erro != nil {
+		return nil, nil, erro
+	}
+
+	if len(mencoes) == 0 {
+		return nil, nil, nil
+	}
+
+	nicks := make([]interface{}, 0, len(mencoes))
+	marcadores := make([]string, 0, len(mencoes))
+	for _, mencao := range mencoes {
+		nicks = append(nicks, mencao.Nick)
+		marcadores = append(marcadores, "?")
+	}
+
+	usuarios, erro := consultas.Query(
+		"select u.id, u.nick from usuarios u where u.nick in ("+strings.Join(marcadores, ",")+") and "+semBloqueioCom("u.id"),
+		append(nicks, autorID, autorID)...,
+	)
+	if erro != nil {
+		return nil, nil, erro
+	}
+
+	// A collation do nick não diferencia maiúsculas, então o mapa também não
+	encontrados := map[string]modelos.Mencao{}
+	for usuarios.Next() {
+		var usuario modelos.Mencao
+		if erro = usuarios.Scan(&usuario.UsuarioID, &usuario.Nick); erro != nil {
+			usuarios.Close()
+			return nil, nil, erro
+		}
+		encontrados[strings.ToLower(usuario.Nick)] = usuario
+	}
+	usuarios.Close()
+
+	var resolvidas []modelos.Mencao
+	var novos []uint64
+	notificados := map[uint64]bool{}
+
+	for _, mencao := range mencoes {
+		usuario, existe := encontrados[strings.ToLower(mencao.Nick)]
+		if !existe {
+			continue
+		}
+
+		mencao.UsuarioID = usuario.UsuarioID
+		mencao.Nick = usuario.Nick
+
+		if _, erro = consultas.Exec(
+			"insert into mencoes (publicacao_id, usuario_id, campo, inicio, fim) values (?, ?, ?, ?, ?)",
+			publicacaoID, mencao.UsuarioID, mencao.Campo, mencao.Inicio, mencao.Fim,
+		); erro != nil {
+			return nil, nil, erro
+		}
+		resolvidas = append(resolvidas, mencao)
+
+		if mencao.UsuarioID != autorID && !anteriores[mencao.UsuarioID] && !notificados[mencao.UsuarioID] {
+			notificados[mencao.UsuarioID] = true
+			novos = append(novos, mencao.UsuarioID)
+		}
+	}
+	return resolvidas, novos, nil
+}
+
+// BuscarPorTag traz as publicações com a tag que o leitor pode ver
+func (repositorio Publicacoes) BuscarPorTag(tag string, leitorID uint64) ([]modelos.Publicacao, error) {
+	linhas, erro := repositorio.db.Query(`
+		select `+colunasPublicacao+` from publicacao p
+		inner join usuarios u on u.id = p.autor_id
+		inner join publicacao_tags pt on pt.publicacao_id = p.id
+		inner join tags t on t.id = pt.tag_id
+		where t.nome = ? and `+publicacaoVisivel+`
+		order by p.id desc
+	`, append([]interface{}{tag}, leitor(leitorID)...)...)
+	if erro != nil {
+		return nil, erro
+	}
+	defer linhas.Close()
+
+	var publicacoes []modelos.Publicacao
+
+	for linhas.Next() {
+		var publicacao modelos.Publicacao
+		if erro = escanearPublicacao(linhas, &publicacao); erro != nil {
+			return nil, erro
+		}
+		publicacoes = append(publicacoes, publicacao)
+	}
+
+	if erro = repositorio.completar(publicacoes, leitorID); erro != nil {
+		return nil, erro
+	}
+	return publicacoes, nil
+}
+
+// Repostar coloca a publicação no feed dos seguidores do usuário. Repostar de novo não faz nada
+func (repositorio Publicacoes) Repostar(publicacaoID, usuarioID uint64) error {
+	_, erro := repositorio.db.Exec(
+		"insert ignore into repostagens (usuario_id, publicacao_id) values (?, ?)", usuarioID, publicacaoID,
+	)
+	return erro
+}
+
+// DesfazerRepostagem tira a repostagem do usuário
+func (repositorio Publicacoes) DesfazerRepostagem(publicacaoID, usuarioID uint64) error {
+	resultado, erro := repositorio.db.Exec(
+		"delete from repostagens where usuario_id = ? and publicacao_id = ?", usuarioID, publicacaoID,
+	)
+	if erro != nil {
+		return erro
+	}
+
+	return conferirAlteracao(resultado)
+}
+
+// VincularMidias troca as mídias anexadas à publicação, mantendo a ordem informada
+func (repositorio Publicacoes) VincularMidias(publicacaoID uint64, midias []uint64) error {
+	return vincularMidias(repositorio.db, publicacaoID, midias)
+}
+
+func vincularMidias(consultas executor, publicacaoID uint64, midias []uint64) error {
+	if _, erro := consultas.Exec("delete from publicacao_midias where publicacao_id = ?", publicacaoID); erro != nil {
+		return erro
+	}
+
+	statement, erro := consultas.Prepare("insert into publicacao_midias (publicacao_id, midia_id, posicao) values (?, ?, ?)")
+	if erro != nil {
+		return erro
+	}
+
+	defer statement.Close()
+
+	for posicao, midiaID := range midias {
+		if _, erro = statement.Exec(publicacaoID, midiaID, posicao); erro != nil {
+			return erro
+		}
+	}
+	return nil
+}
+
+// completar carrega as mídias, as tags e as citações das publicações buscadas
+func (repositorio Publicacoes) completar(publicacoes []modelos.Publicacao, leitorID uint64) error {
+	if erro := repositorio.carregarMidias(publicacoes); erro != nil {
+		return erro
+	}
+	if erro := repositorio.carregarTags(publicacoes); erro != nil {
+		return erro
+	}
+	if erro := repositorio.carregarMencoes(publicacoes); erro != nil {
+		return erro
+	}
+	return repositorio.carregarCitacoes(publicacoes, leitorID)
+}
+
+// carregarMencoes preenche as menções de cada publicação com o nick atual do mencionado
+func (repositorio Publicacoes) carregarMencoes(publicacoes []modelos.Publicacao) error {
+	if len(publicacoes) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, 0, len(publicacoes))
+	indices := map[uint64][]int{}
+	for i, publicacao := range publicacoes {
+		ids = append(ids, publicacao.ID)
+		indices[publicacao.ID] = append(indices[publicacao.ID], i)
+	}
+
+	marcadores, argumentos := emLista(ids)
+	linhas, erro := repositorio.db.Query(`
+		select m.publicacao_id, m.usuario_id, u.nick, m.campo, m.inicio, m.fim from mencoes m
+		inner join usuarios u on u.id = m.usuario_id
+		where m.publicacao_id in (`+marcadores+`)
+		order by m.publicacao_id, m.campo desc, m.inicio
+	`, argumentos...)
+	if erro != nil {
+		return erro
+	}
+
+	defer linhas.Close()
+
+	for linhas.Next() {
+		var publicacaoID uint64
+		var mencao modelos.Mencao
+		if erro = linhas.Scan(&publicacaoID, &mencao.UsuarioID, &mencao.Nick, &mencao.Campo, &mencao.Inicio, &mencao.Fim); erro != nil {
+			return erro
+		}
+
+		for _, i := range indices[publicacaoID] {
+			publicacoes[i].Mencoes = append(publicacoes[i].Mencoes, mencao)
+		}
+	}
+	return nil
+}
+
+// carregarTags preenche as tags de cada publicação
+func (repositorio Publicacoes) carregarTags(publicacoes []modelos.Publicacao) error {
+	if len(publicacoes) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, 0, len(publicacoes))
+	indices := map[uint64][]int{}
+	for i, publicacao := range publicacoes {
+		ids = append(ids, publicacao.ID)
+		indices[publicacao.ID] = append(indices[publicacao.ID], i)
+	}
+
+	marcadores, argumentos := emLista(ids)
+	linhas, erro := repositorio.db.Query(`
+		select pt.publicacao_id, t.nome from publicacao_tags pt
+		inner join tags t on t.id = pt.tag_id
+		where pt.publicacao_id in (`+marcadores+`)
+		order by pt.publicacao_id, pt.posicao
+	`, argumentos...)
+	if erro != nil {
+		return erro
+	}
+
+	defer linhas.Close()
+
+	for linhas.Next() {
+		var publicacaoID uint64
+		var tag string
+		if erro = linhas.Scan(&publicacaoID, &tag); erro != nil {
+			return erro
+		}
+
+		for _, i := range indices[publicacaoID] {
+			publicacoes[i].Tags = append(publicacoes[i].Tags, tag)
+		}
+	}
+	return nil
+}
+
+// carregarCitacoes preenche a publicação citada de cada publicação, quando o leitor pode vê-la.
+// As citadas não trazem as próprias citações, só o CitacaoID
+func (repositorio Publicacoes) carregarCitacoes(publicacoes []modelos.Publicacao, leitorID uint64) error {
+	var ids []uint64
+	for _, publicacao := range publicacoes {
+		if publicacao.CitacaoID != nil {
+			ids = append(ids, *publicacao.CitacaoID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	marcadores, argumentos := emLista(ids)
+	linhas, erro := repositorio.db.Query(`
+		select `+colunasPublicacao+` from publicacao p
+		inner join usuarios u on u.id = p.autor_id
+		where p.id in (`+marcadores+`) and `+publicacaoVisivel,
+		append(argumentos, leitor(leitorID)...)...,
+	)
+	if erro != nil {
+		return erro
+	}
+
+	defer linhas.Close()
+
+	var citadas []modelos.Publicacao
+	for linhas.Next() {
+		var citada modelos.Publicacao
+		if erro = escanearPublicacao(linhas, &citada); erro != nil {
+			return erro
+		}
+		citadas = append(citadas, citada)
+	}
+
+	if erro = repositorio.carregarMidias(citadas); erro != nil {
+		return erro
+	}
+
+	porID := map[uint64]*modelos.Publicacao{}
+	for i := range citadas {
+		porID[citadas[i].ID] = &citadas[i]
+	}
+
+	for i := range publicacoes {
+		if publicacoes[i].CitacaoID != nil {
+			publicacoes[i].Citacao = porID[*publicacoes[i].CitacaoID]
+		}
+	}
+	return nil
+}
+
+// emLista monta os marcadores e argumentos de uma cláusula in, sem ids repetidos
+func emLista(ids []uint64) (string, []interface{}) {
+	marcadores := make([]string, 0, len(ids))
+	argumentos := make([]interface{}, 0, len(ids))
+	repetidos := map[uint64]bool{}
+
+	for _, id := range ids {
+		if repetidos[id] {
+			continue
+		}
+		repetidos[id] = true
+		marcadores = append(marcadores, "?")
+		argumentos = append(argumentos, id)
+	}
+	return strings.Join(marcadores, ","), argumentos
+}
+
+// carregarMidias preenche os ids das mídias de cada publicação com uma única consulta
+func (repositorio Publicacoes) carregarMidias(publicacoes []modelos.Publicacao) error {
+	if len(publicacoes) == 0 {
+		return nil
+	}
+
+	indices := map[uint64][]int{}
+	marcadores := make([]string, 0, len(publicacoes))
+	argumentos := make([]interface{}, 0, len(publicacoes))
+
+	for i, publicacao := range publicacoes {
+		if _, repetida := indices[publicacao.ID]; !repetida {
+			marcadores = append(marcadores, "?")
+			argumentos = append(argumentos, publicacao.ID)
+		}
+		indices[publicacao.ID] = append(indices[publicacao.ID], i)
+	}
+
+	linhas, erro := repositorio.db.Query(fmt.Sprintf(`
+		select publicacao_id, midia_id from publicacao_midias
+		where publicacao_id in (%s)
+		order by publicacao_id, posicao
+	`, strings.Join(marcadores, ",")), argumentos...)
+	if erro != nil {
+		return erro
+	}
+
+	defer linhas.Close()
+
+	for linhas.Next() {
+		var publicacaoID, midiaID uint64
+		if erro = linhas.Scan(&publicacaoID, &midiaID); erro != nil {
+			return erro
+		}
+
+		for _, i := range indices[publicacaoID] {
+			publicacoes[i].Midias = append(publicacoes[i].Midias, midiaID)
+		}
+	}
+	return nil
+}
+
+// escanearPublicacao lê uma linha selecionada com colunasPublicacao
+func escanearPublicacao(linhas *sql.Rows, publicacao *modelos.Publicacao) error {
+	var citacaoID sql.NullInt64
+
+	if erro := linhas.Scan(
+		&publicacao.ID,
+		&publicacao.Titulo,
+		&publicacao.Conteudo,
+		&publicacao.AutorID,
+		&publicacao.Curtidas,
+		&publicacao.Repostagens,
+		&publicacao.Visibilidade,
+		&citacaoID,
+		&publicacao.Versao,
+		&publicacao.CriadaEm,
+		&publicacao.AutorNick,
+	); erro != nil {
+		return erro
+	}
+
+	if citacaoID.Valid {
+		id := uint64(citacaoID.Int64)
+		publicacao.CitacaoID = &id
+	}
+	return nil
+}
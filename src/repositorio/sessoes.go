@@ -0,0 +1,163 @@
+package repositorio
+
+import (
+	"api/src/modelos"
+	"database/sql"
+)
+
+type Sessoes struct {
+	db *sql.DB
+}
+
+// NovoRepositorioDeSessoes cria uma estancia nova de repositorio para sessoes
+func NovoRepositorioDeSessoes(db *sql.DB) *Sessoes {
+	return &Sessoes{db}
+}
+
+// Criar insere uma sessão no banco de dados
+func (repositorio Sessoes) Criar(sessao modelos.Sessao) error {
+	statement, erro := repositorio.db.Prepare(
+		"insert into sessoes (id, usuario_id, user_agent, ip, expiraEm) values (?, ?, ?, ?, ?)",
+	)
+	if erro != nil {
+		return erro
+	}
+
+	defer statement.Close()
+
+	if _, erro = statement.Exec(sessao.ID, sessao.UsuarioID, sessao.UserAgent, sessao.IP, sessao.ExpiraEm); erro != nil {
+		return erro
+	}
+	return nil
+}
+
+// EstaAtiva verifica se a sessão do usuário não foi revogada nem expirou
+func (repositorio Sessoes) EstaAtiva(sessaoID string, usuarioID uint64) (bool, error) {
+	linha, erro := repositorio.db.Query(`
+		select 1 from sessoes
+		where id = ? and usuario_id = ? and revogadaEm is null and expiraEm > now()
+	`, sessaoID, usuarioID)
+	if erro != nil {
+		return false, erro
+	}
+
+	defer linha.Close()
+
+	return linha.Next(), nil
+}
+
+// RegistrarAcesso atualiza o último acesso da sessão, no máximo uma vez por minuto
+func (repositorio Sessoes) RegistrarAcesso(sessaoID string) error {
+	statement, erro := repositorio.db.Prepare(`
+		update sessoes set ultimoAcessoEm = now()
+		where id = ? and ultimoAcessoEm < now() - interval 1 minute
+	`)
+	if erro != nil {
+		return erro
+	}
+
+	defer statement.Close()
+
+	if _, erro = statement.Exec(sessaoID); erro != nil {
+		return erro
+	}
+	return nil
+}
+
+// DispositivoConhecido verifica se o usuário já fez login com o mesmo user agent
+func (repositorio Sessoes) DispositivoConhecido(usuarioID uint64, userAgent string) (bool, error) {
+	linha, erro := repositorio.db.Query(
+		"select 1 from sessoes where usuario_id = ? and user_agent = ? limit 1", usuarioID, userAgent,
+	)
+	if erro != nil {
+		return false, erro
+	}
+
+	defer linha.Close()
+
+	return linha.Next(), nil
+}
+
+// PrimeiroLogin verifica se o usuário nunca teve uma sessão
+func (repositorio Sessoes) PrimeiroLogin(usuarioID uint64) (bool, error) {
+	linha, erro := repositorio.db.Query("select 1 from sessoes where usuario_id = ? limit 1", usuarioID)
+	if erro != nil {
+		return false, erro
+	}
+
+	defer linha.Close()
+
+	return !linha.Next(), nil
+}
+
+// BuscarAtivas traz as sessões ativas de um usuário
+func (repositorio Sessoes) BuscarAtivas(usuarioID uint64) ([]modelos.Sessao, error) {
+	linhas, erro := repositorio.db.Query(`
+		select id, user_agent, ip, criadaEm, ultimoAcessoEm, expiraEm from sessoes
+		where usuario_id = ? and revogadaEm is null and expiraEm > now()
+		order by ultimoAcessoEm desc
+	`, usuarioID)
+	if erro != nil {
+		return nil, erro
+	}
+
+	defer linhas.Close()
+
+	var sessoes []modelos.Sessao
+
+	for linhas.Next() {
+		sessao := modelos.Sessao{UsuarioID: usuarioID}
+		if erro = linhas.Scan(
+			&sessao.ID,
+			&sessao.UserAgent,
+			&sessao.IP,
+			&sessao.CriadaEm,
+			&sessao.UltimoAcessoEm,
+			&sessao.ExpiraEm,
+		); erro != nil {
+			return nil, erro
+		}
+		sessoes = append(sessoes, sessao)
+	}
+	return sessoes, nil
+}
+
+// Revogar encerra uma sessão do usuário e informa se ela existia
+func (repositorio Sessoes) Revogar(sessaoID string, usuarioID uint64) (bool, error) {
+	statement, erro := repositorio.db.Prepare(
+		"update sessoes set revogadaEm = now() where id = ? and usuario_id = ? and revogadaEm is null",
+	)
+	if erro != nil {
+		return false, erro
+	}
+
+	defer statement.Close()
+
+	resultado, erro := statement.Exec(sessaoID, usuarioID)
+	if erro != nil {
+		return false, erro
+	}
+
+	linhasAfetadas, erro := resultado.RowsAffected()
+	if erro != nil {
+		return false, erro
+	}
+	return linhasAfetadas > 0, nil
+}
+
+// RevogarOutras encerra todas as sessões do usuário exceto a informada
+func (repositorio Sessoes) RevogarOutras(usuarioID uint64, sessaoAtualID string) error {
+	statement, erro := repositorio.db.Prepare(
+		"update sessoes set revogadaEm = now() where usuario_id = ? and id <> ? and revogadaEm is null",
+	)
+	if erro != nil {
+		return erro
+	}
+
+	defer statement.Close()
+
+	if _, erro = statement.Exec(usuarioID, sessaoAtualID); erro != nil {
+		return erro
+	}
+	return nil
+}
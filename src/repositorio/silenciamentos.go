@@ -0,0 +1,174 @@
+package repositorio
+
+import (
+	"api/src/modelos"
+	"database/sql"
+)
+
+type Silenciamentos struct {
+	db *sql.DB
+}
+
+// NovoRepositorioDeSilenciamentos cria uma estancia nova de repositorio para silenciamentos
+func NovoRepositorioDeSilenciamentos(db *sql.DB) *Silenciamentos {
+	return &Silenciamentos{db}
+}
+
+// foraDosSilenciados filtra do feed as publicações de usuários e termos silenciados pelo
+// leitor, passado como parâmetro três vezes. O leitor sempre vê as próprias publicações
+const foraDosSilenciados = `not exists (
+	select 1 from usuarios_silenciados si
+	where si.usuario_id = ? and si.silenciado_id = p.autor_id
+	and (si.expiraEm is null or si.expiraEm > now())
+) and (p.autor_id = ? or not exists (
+	select 1 from palavras_silenciadas ps
+	where ps.usuario_id = ?
+	and (ps.expiraEm is null or ps.expiraEm > now())
+	and (locate(ps.termo, lower(p.titulo)) > 0 or locate(ps.termo, lower(p.conteudo)) > 0)
+))`
+
+// SilenciarUsuario tira um usuário do feed sem deixar de segui-lo. Silenciar de novo troca
+// a expiração. Retorna ErrNaoEncontrado quando o usuário silenciado não existe
+func (repositorio Silenciamentos) SilenciarUsuario(usuarioID uint64, silenciamento modelos.Silenciamento) error {
+	resultado, erro := repositorio.db.Exec(`
+		insert into usuarios_silenciados (usuario_id, silenciado_id, expiraEm)
+		select ?, id, ? from usuarios where id = ?
+		on duplicate key update expiraEm = values(expiraEm)
+	`, usuarioID, silenciamento.ExpiraEm, silenciamento.UsuarioID)
+	if erro != nil {
+		return erro
+	}
+
+	// Com on duplicate key uma linha igual à salva conta como zero, então confere se o usuário existe
+	linhas, erro := resultado.RowsAffected()
+	if erro != nil {
+		return erro
+	}
+
+	if linhas == 0 {
+		var existe bool
+		if erro = repositorio.db.QueryRow(
+			"select exists (select 1 from usuarios where id = ?)", silenciamento.UsuarioID,
+		).Scan(&existe); erro != nil {
+			return erro
+		}
+		if !existe {
+			return ErrNaoEncontrado
+		}
+	}
+	return nil
+}
+
+// DessilenciarUsuario devolve o usuário ao feed
+func (repositorio Silenciamentos) DessilenciarUsuario(usuarioID, silenciadoID uint64) error {
+	resultado, erro := repositorio.db.Exec(
+		"delete from usuarios_silenciados where usuario_id = ? and silenciado_id = ?", usuarioID, silenciadoID,
+	)
+	if erro != nil {
+		return erro
+	}
+
+	return conferirAlteracao(resultado)
+}
+
+// BuscarUsuarios traz os usuários silenciados que ainda não expiraram
+func (repositorio Silenciamentos) BuscarUsuarios(usuarioID uint64) ([]modelos.Silenciamento, error) {
+	linhas, erro := repositorio.db.Query(`
+		select u.id, u.nick, si.expiraEm, si.criadoEm from usuarios_silenciados si
+		inner join usuarios u on u.id = si.silenciado_id
+		where si.usuario_id = ? and (si.expiraEm is null or si.expiraEm > now())
+		order by si.criadoEm desc
+	`, usuarioID)
+	if erro != nil {
+		return nil, erro
+	}
+	defer linhas.Close()
+
+	var silenciamentos []modelos.Silenciamento
+
+	for linhas.Next() {
+		var silenciamento modelos.Silenciamento
+		var expiraEm sql.NullTime
+
+		if erro = linhas.Scan(
+			&silenciamento.UsuarioID,
+			&silenciamento.Nick,
+			&expiraEm,
+			&silenciamento.CriadoEm,
+		); erro != nil {
+			return nil, erro
+		}
+
+		if expiraEm.Valid {
+			silenciamento.ExpiraEm = &expiraEm.Time
+		}
+		silenciamentos = append(silenciamentos, silenciamento)
+	}
+	return silenciamentos, nil
+}
+
+// SilenciarTermo tira do feed as publicações que contêm a palavra ou frase. Silenciar um
+// termo que já existe troca a expiração
+func (repositorio Silenciamentos) SilenciarTermo(usuarioID uint64, silenciamento modelos.Silenciamento) (uint64, error) {
+	resultado, erro := repositorio.db.Exec(`
+		insert into palavras_silenciadas (usuario_id, termo, expiraEm) values (?, ?, ?)
+		on duplicate key update id = last_insert_id(id), expiraEm = values(expiraEm)
+	`, usuarioID, silenciamento.Termo, silenciamento.ExpiraEm)
+	if erro != nil {
+		return 0, erro
+	}
+
+	id, erro := resultado.LastInsertId()
+	if erro != nil {
+		return 0, erro
+	}
+
+	return uint64(id), nil
+}
+
+// DessilenciarTermo remove um termo silenciado do usuário
+func (repositorio Silenciamentos) DessilenciarTermo(usuarioID, termoID uint64) error {
+	resultado, erro := repositorio.db.Exec(
+		"delete from palavras_silenciadas where id = ? and usuario_id = ?", termoID, usuarioID,
+	)
+	if erro != nil {
+		return erro
+	}
+
+	return conferirAlteracao(resultado)
+}
+
+// BuscarTermos traz as palavras e frases silenciadas que ainda não expiraram
+func (repositorio Silenciamentos) BuscarTermos(usuarioID uint64) ([]modelos.Silenciamento, error) {
+	linhas, erro := repositorio.db.Query(`
+		select id, termo, expiraEm, criadoEm from palavras_silenciadas
+		where usuario_id = ? and (expiraEm is null or expiraEm > now())
+		order by criadoEm desc
+	`, usuarioID)
+	if erro != nil {
+		return nil, erro
+	}
+	defer linhas.Close()
+
+	var silenciamentos []modelos.Silenciamento
+
+	for linhas.Next() {
+		var silenciamento modelos.Silenciamento
+		var expiraEm sql.NullTime
+
+		if erro = linhas.Scan(
+			&silenciamento.ID,
+			&silenciamento.Termo,
+			&expiraEm,
+			&silenciamento.CriadoEm,
+		); erro != nil {
+			return nil, erro
+		}
+
+		if expiraEm.Valid {
+			silenciamento.ExpiraEm = &expiraEm.Time
+		}
+		silenciamentos = append(silenciamentos, silenciamento)
+	}
+	return silenciamentos, nil
+}
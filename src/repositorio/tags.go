@@ -0,0 +1,95 @@
+package repositorio
+
+import (
+	"api/src/modelos"
+	"database/sql"
+	"strings"
+)
+
+type Tags struct {
+	db *sql.DB
+}
+
+// NovoRepositorioDeTags cria uma estancia nova de repositorio para tags
+func NovoRepositorioDeTags(db *sql.DB) *Tags {
+	return &Tags{db}
+}
+
+// BuscarPorPrefixo traz as tags que começam com o prefixo, das mais usadas para as menos usadas
+func (repositorio Tags) BuscarPorPrefixo(prefixo string, limite int) ([]modelos.Tag, error) {
+	linhas, erro := repositorio.db.Query(`
+		select t.id, t.nome, count(pt.publicacao_id) as publicacoes from tags t
+		left join publicacao_tags pt on pt.tag_id = t.id
+		where t.nome like ?
+		group by t.id, t.nome
+		order by publicacoes desc, t.nome
+		limit ?
+	`, escaparLike(prefixo)+"%", limite)
+	if erro != nil {
+		return nil, erro
+	}
+	defer linhas.Close()
+
+	return escanearTags(linhas)
+}
+
+// Seguir faz as publicações com a tag aparecerem no feed do usuário
+func (repositorio Tags) Seguir(usuarioID uint64, tag string) error {
+	if _, erro := repositorio.db.Exec("insert ignore into tags (nome) values (?)", tag); erro != nil {
+		return erro
+	}
+
+	_, erro := repositorio.db.Exec(`
+		insert ignore into tags_seguidas (usuario_id, tag_id)
+		select ?, id from tags where nome = ?
+	`, usuarioID, tag)
+	return erro
+}
+
+// PararDeSeguir tira as publicações com a tag do feed do usuário
+func (repositorio Tags) PararDeSeguir(usuarioID uint64, tag string) error {
+	resultado, erro := repositorio.db.Exec(`
+		delete ts from tags_seguidas ts
+		inner join tags t on t.id = ts.tag_id
+		where ts.usuario_id = ? and t.nome = ?
+	`, usuarioID, tag)
+	if erro != nil {
+		return erro
+	}
+
+	return conferirAlteracao(resultado)
+}
+
+// BuscarSeguidas traz as tags seguidas pelo usuário
+func (repositorio Tags) BuscarSeguidas(usuarioID uint64) ([]modelos.Tag, error) {
+	linhas, erro := repositorio.db.Query(`
+		select t.id, t.nome, (select count(*) from publicacao_tags pt where pt.tag_id = t.id) from tags t
+		inner join tags_seguidas ts on ts.tag_id = t.id
+		where ts.usuario_id = ?
+		order by t.nome
+	`, usuarioID)
+	if erro != nil {
+		return nil, erro
+	}
+	defer linhas.Close()
+
+	return escanearTags(linhas)
+}
+
+func escanearTags(linhas *sql.Rows) ([]modelos.Tag, error) {
+	var tags []modelos.Tag
+
+	for linhas.Next() {
+		var tag modelos.Tag
+		if erro := linhas.Scan(&tag.ID, &tag.Nome, &tag.Publicacoes); erro != nil {
+			return nil, erro
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// escaparLike impede que % e _ do texto funcionem como curingas em um like
+func escaparLike(texto string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(texto)
+}
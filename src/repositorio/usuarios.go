@@ -4,6 +4,7 @@ import (
 	"api/src/modelos"
 	"database/sql"
 	"fmt"
+	"strings"
 )
 
 type Usuarios struct {
@@ -17,7 +18,10 @@ func NovoRepositoriosDeUsuarios(db *sql.DB) *Usuarios {
 
 // Criar insere um usuario no banco de dados
 func (repositorio Usuarios) Criar(usuario modelos.Usuario) (uint64, error) {
-	statement, erro := repositorio.db.Prepare("Insert Into usuarios(nome,nick,email,senha) values (?, ?, ?, ?)")
+	statement, erro := repositorio.db.Prepare(`
+		Insert Into usuarios(nome, nick, email, senha, bio, avatar_url, capa_url, localizacao, website, data_nascimento, privada)
+		values (?, ?, ?, ?, nullif(?, ''), nullif(?, ''), nullif(?, ''), nullif(?, ''), nullif(?, ''), nullif(?, ''), ?)
+	`)
 
 	if erro != nil {
 		return 0, erro
@@ -25,7 +29,11 @@ func (repositorio Usuarios) Criar(usuario modelos.Usuario) (uint64, error) {
 
 	defer statement.Close()
 
-	resultado, erro := statement.Exec(usuario.Nome, usuario.Nick, usuario.Email, usuario.Senha)
+	resultado, erro := statement.Exec(
+		usuario.Nome, usuario.Nick, usuario.Email, usuario.Senha,
+		usuario.Bio, usuario.AvatarURL, usuario.CapaURL, usuario.Localizacao, usuario.Website, usuario.DataNascimento,
+		usuario.Privada,
+	)
 
 	if erro != nil {
 		return 0, erro
@@ -40,10 +48,13 @@ func (repositorio Usuarios) Criar(usuario modelos.Usuario) (uint64, error) {
 	return uint64(ultimoID), nil
 }
 
-// Buscar traz todos os usuarios baseados em nome ou nick
-func (repositorio Usuarios) Buscar(nomeOuNick string) ([]modelos.Usuario, error) {
+// Buscar traz todos os usuarios baseados em nome ou nick, sem os que têm bloqueio com o leitor
+func (repositorio Usuarios) Buscar(nomeOuNick string, leitorID uint64) ([]modelos.Usuario, error) {
 	nomeOuNick = fmt.Sprintf("%%%s%%", nomeOuNick)
-	linhas, erro := repositorio.db.Query("Select u.id, u.nome, u.nick, u.email, u.criadoEm from usuarios u where nome like ? or nick like ?", nomeOuNick, nomeOuNick)
+	linhas, erro := repositorio.db.Query(
+		"Select u.id, u.nome, u.nick, u.email, u.criadoEm from usuarios u where (nome like ? or nick like ?) and "+semBloqueioCom("u.id"),
+		nomeOuNick, nomeOuNick, leitorID, leitorID,
+	)
 
 	if erro != nil {
 		return nil, erro
@@ -73,7 +84,10 @@ func (repositorio Usuarios) Buscar(nomeOuNick string) ([]modelos.Usuario, error)
 // BuscarPorId traz um registro baseado em ID
 func (repositorio Usuarios) BuscarPorID(usuarioID uint64) (modelos.Usuario, error) {
 
-	linhas, erro := repositorio.db.Query("Select id,nome, nick, email, criadoEm from usuarios where id = ? ", usuarioID)
+	linhas, erro := repositorio.db.Query(`
+		Select id, nome, nick, email, bio, avatar_url, capa_url, localizacao, website, data_nascimento, privada, versao, criadoEm
+		from usuarios where id = ?
+	`, usuarioID)
 
 	if erro != nil {
 		return modelos.Usuario{}, erro
@@ -83,54 +97,92 @@ func (repositorio Usuarios) BuscarPorID(usuarioID uint64) (modelos.Usuario, erro
 	var usuario modelos.Usuario
 
 	if linhas.Next() {
+		var bio, avatarURL, capaURL, localizacao, website sql.NullString
+		var dataNascimento sql.NullTime
+
 		if erro = linhas.Scan(
 			&usuario.ID,
 			&usuario.Nome,
 			&usuario.Nick,
 			&usuario.Email,
+			&bio,
+			&avatarURL,
+			&capaURL,
+			&localizacao,
+			&website,
+			&dataNascimento,
+			&usuario.Privada,
+			&usuario.Versao,
 			&usuario.CriadoEm,
 		); erro != nil {
 			return modelos.Usuario{}, erro
 		}
+
+		usuario.Bio = texto(bio)
+		usuario.AvatarURL = texto(avatarURL)
+		usuario.CapaURL = texto(capaURL)
+		usuario.Localizacao = texto(localizacao)
+		usuario.Website = texto(website)
+		if dataNascimento.Valid {
+			data := dataNascimento.Time.Format(modelos.FormatoData)
+			usuario.DataNascimento = &data
+		}
 	}
 	return usuario, nil
 }
 
-// Atualizar altera as informações de um usuário no banco de dados
-func (repositorio Usuarios) Atualizar(usuarioID uint64, usuario modelos.Usuario) error {
-	statement, erro := repositorio.db.Prepare("update usuarios set nome =?, nick=?, email = ? where id = ?")
-
+// Atualizar altera as informações de um usuário no banco de dados e incrementa a sua versão.
+// Com uma versão diferente de zero a alteração só acontece se o usuário ainda estiver nela
+func (repositorio Usuarios) Atualizar(usuarioID uint64, usuario modelos.Usuario, versao uint64) error {
+	// Substitui todos os campos do perfil; ausentes (nil) ou vazios removem a informação
+	resultado, erro := repositorio.db.Exec(`
+		update usuarios set nome =?, nick=?, email = ?,
+		bio = nullif(?, ''),
+		avatar_url = nullif(?, ''),
+		capa_url = nullif(?, ''),
+		localizacao = nullif(?, ''),
+		website = nullif(?, ''),
+		data_nascimento = nullif(?, ''),
+		privada = ?,
+		versao = versao + 1
+		where id = ? and (? = 0 or versao = ?)
+	`,
+		usuario.Nome, usuario.Nick, usuario.Email,
+		usuario.Bio, usuario.AvatarURL, usuario.CapaURL, usuario.Localizacao, usuario.Website,
+		usuario.DataNascimento,
+		usuario.Privada,
+		usuarioID, versao, versao,
+	)
 	if erro != nil {
 		return erro
 	}
 
-	defer statement.Close()
-
-	if _, erro = statement.Exec(usuario.Nome, usuario.Nick, usuario.Email, usuarioID); erro != nil {
-		return erro
-	}
-	return nil
+	return conferirVersao(resultado, versao)
 }
 
-// Deletar remove um usuario do banco de dados
-func (repositorio Usuarios) Deletar(usuarioId uint64) error {
-	statement, erro := repositorio.db.Prepare("delete from usuarios where id=?")
+// Deletar exclui um usuário do banco, respeitando a versão da mesma forma que Atualizar
+func (repositorio Usuarios) Deletar(usuarioId uint64, versao uint64) error {
+	resultado, erro := repositorio.db.Exec(
+		"delete from usuarios where id = ? and (? = 0 or versao = ?)", usuarioId, versao, versao,
+	)
 	if erro != nil {
 		return erro
 	}
 
-	defer statement.Close()
+	return conferirVersao(resultado, versao)
+}
 
-	if _, erro = statement.Exec(usuarioId); erro != nil {
-		return erro
+// BuscarPorLogin busca um usuário por email ou nick e retorna o seu id, nick, email e senha com hash.
+// Nicks não podem ter @, então o identificador decide qual índice único é consultado
+func (repositorio Usuarios) BuscarPorLogin(identificador string) (modelos.Usuario, error) {
+	coluna := "nick"
+	if strings.Contains(identificador, "@") {
+		coluna = "email"
 	}
 
-	return nil
-}
-
-// BuscarPorEmail busca um usuário por email e retorna o seu id e senha com hash
-func (repositorio Usuarios) BuscaPorEmail(email string) (modelos.Usuario, error) {
-	linha, erro := repositorio.db.Query("Select id,senha from usuarios where email = ?", email)
+	linha, erro := repositorio.db.Query(
+		fmt.Sprintf("Select id, nick, email, senha from usuarios where %s = ?", coluna), identificador,
+	)
 
 	if erro != nil {
 		return modelos.Usuario{}, erro
@@ -141,27 +193,32 @@ func (repositorio Usuarios) BuscaPorEmail(email string) (modelos.Usuario, error)
 	var usuario modelos.Usuario
 
 	if linha.Next() {
-		if erro = linha.Scan(&usuario.ID, &usuario.Senha); erro != nil {
+		if erro = linha.Scan(&usuario.ID, &usuario.Nick, &usuario.Email, &usuario.Senha); erro != nil {
 			return modelos.Usuario{}, erro
 		}
 	}
 	return usuario, nil
 }
 
-// Seguir permite um usuário seguir outro
-func (repositorio Usuarios) Seguir(usuarioId, seguidorID uint64) error {
-	statement, erro := repositorio.db.Prepare("Insert ignore into seguidores(usuario_id, seguidor_id) values(?,?)")
-
-	if erro != nil {
-		return erro
+// Seguir faz um usuário seguir outro. Contas privadas recebem uma solicitação pendente
+// em vez de um seguidor, e o retorno informa se foi esse o caso. Usuários inexistentes ou
+// com bloqueio entre os dois retornam ErrNaoEncontrado
+func (repositorio Usuarios) Seguir(usuarioId, seguidorID uint64) (bool, error) {
+	if _, erro := repositorio.db.Exec(`
+		Insert ignore into seguidores(usuario_id, seguidor_id, pendente)
+		select id, ?, privada from usuarios u where id = ? and `+semBloqueioCom("u.id")+`
+	`, seguidorID, usuarioId, seguidorID, seguidorID); erro != nil {
+		return false, erro
 	}
 
-	defer statement.Close()
-
-	if _, erro = statement.Exec(usuarioId, seguidorID); erro != nil {
-		return erro
+	var pendente bool
+	erro := repositorio.db.QueryRow(
+		"Select pendente from seguidores where usuario_id = ? and seguidor_id = ?", usuarioId, seguidorID,
+	).Scan(&pendente)
+	if erro == sql.ErrNoRows {
+		return false, ErrNaoEncontrado
 	}
-	return nil
+	return pendente, erro
 }
 
 // PararDeSeguir permite um usuário seguir outro
@@ -186,7 +243,7 @@ func (repositorio Usuarios) BuscarSeguidores(usuarioID uint64) ([]modelos.Usuari
 	linhas, erro := repositorio.db.Query(`
 		Select u.id, u.nome, u.nick, u.email, u.criadoEm from usuarios u
 		inner join seguidores s on u.id = s.seguidor_id 
-		where s.usuario_id = ?
+		where s.usuario_id = ? and not s.pendente
 	`, usuarioID)
 
 	if erro != nil {
@@ -219,7 +276,7 @@ func (repositorio Usuarios) BuscarSeguindo(usuarioID uint64) ([]modelos.Usuario,
 	linhas, erro := repositorio.db.Query(`
 		Select u.id, u.nome, u.nick, u.email, u.criadoEm from usuarios u
 		inner join seguidores s on u.id = s.usuario_id 
-		where s.seguidor_id = ?
+		where s.seguidor_id = ? and not s.pendente
 	`, usuarioID)
 
 	if erro != nil {
@@ -280,3 +337,109 @@ func (repositorio Usuarios) AtualizarSenha(usuarioID uint64, senha string) error
 	}
 	return nil
 }
+
+// AtualizarAvatar troca o endereço do avatar do usuário
+func (repositorio Usuarios) AtualizarAvatar(usuarioID uint64, avatarURL string) error {
+	statement, erro := repositorio.db.Prepare("Update usuarios set avatar_url = ?, versao = versao + 1 where id = ?")
+	if erro != nil {
+		return erro
+	}
+	defer statement.Close()
+
+	if _, erro = statement.Exec(avatarURL, usuarioID); erro != nil {
+		return erro
+	}
+	return nil
+}
+
+// BuscarSolicitacoesRecebidas traz os usuários esperando aprovação para seguir o usuário
+func (repositorio Usuarios) BuscarSolicitacoesRecebidas(usuarioID uint64) ([]modelos.Usuario, error) {
+	linhas, erro := repositorio.db.Query(`
+		Select u.id, u.nome, u.nick, u.email, u.criadoEm from usuarios u
+		inner join seguidores s on u.id = s.seguidor_id
+		where s.usuario_id = ? and s.pendente
+		order by s.criadoEm
+	`, usuarioID)
+	if erro != nil {
+		return nil, erro
+	}
+	defer linhas.Close()
+
+	return escanearUsuarios(linhas)
+}
+
+// BuscarSolicitacoesEnviadas traz os usuários que o usuário pediu para seguir e ainda não aprovaram
+func (repositorio Usuarios) BuscarSolicitacoesEnviadas(seguidorID uint64) ([]modelos.Usuario, error) {
+	linhas, erro := repositorio.db.Query(`
+		Select u.id, u.nome, u.nick, u.email, u.criadoEm from usuarios u
+		inner join seguidores s on u.id = s.usuario_id
+		where s.seguidor_id = ? and s.pendente
+		order by s.criadoEm
+	`, seguidorID)
+	if erro != nil {
+		return nil, erro
+	}
+	defer linhas.Close()
+
+	return escanearUsuarios(linhas)
+}
+
+// AprovarSolicitacao transforma a solicitação pendente em seguidor
+func (repositorio Usuarios) AprovarSolicitacao(usuarioID, seguidorID uint64) error {
+	resultado, erro := repositorio.db.Exec(
+		"update seguidores set pendente = false where usuario_id = ? and seguidor_id = ? and pendente",
+		usuarioID, seguidorID,
+	)
+	if erro != nil {
+		return erro
+	}
+
+	return conferirAlteracao(resultado)
+}
+
+// AprovarSolicitacoes aprova todas as solicitações pendentes, usado quando a conta deixa de ser privada
+func (repositorio Usuarios) AprovarSolicitacoes(usuarioID uint64) error {
+	_, erro := repositorio.db.Exec("update seguidores set pendente = false where usuario_id = ? and pendente", usuarioID)
+	return erro
+}
+
+// RecusarSolicitacao remove a solicitação pendente sem criar o seguidor
+func (repositorio Usuarios) RecusarSolicitacao(usuarioID, seguidorID uint64) error {
+	resultado, erro := repositorio.db.Exec(
+		"delete from seguidores where usuario_id = ? and seguidor_id = ? and pendente", usuarioID, seguidorID,
+	)
+	if erro != nil {
+		return erro
+	}
+
+	return conferirAlteracao(resultado)
+}
+
+// escanearUsuarios lê as linhas com id, nome, nick, email e criadoEm
+func escanearUsuarios(linhas *sql.Rows) ([]modelos.Usuario, error) {
+	var usuarios []modelos.Usuario
+
+	for linhas.Next() {
+		var usuario modelos.Usuario
+
+		if erro := linhas.Scan(
+			&usuario.ID,
+			&usuario.Nome,
+			&usuario.Nick,
+			&usuario.Email,
+			&usuario.CriadoEm,
+		); erro != nil {
+			return nil, erro
+		}
+		usuarios = append(usuarios, usuario)
+	}
+	return usuarios, nil
+}
+
+// texto converte uma coluna opcional em ponteiro, nil quando a coluna é nula
+func texto(valor sql.NullString) *string {
+	if !valor.Valid {
+		return nil
+	}
+	return &valor.String
+}
@@ -2,6 +2,7 @@ package respostas
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 )
@@ -18,11 +19,26 @@ func JSON(w http.ResponseWriter, statusCode int, dados interface{}) {
 	}
 }
 
+// errosPorCampo é implementado pelos erros de validação que detalham cada campo
+type errosPorCampo interface {
+	error
+	Campos() map[string][]string
+}
+
 // Erro retorna um erro em formato JSON
 func Erro(w http.ResponseWriter, statusCode int, erro error) {
+	var campos map[string][]string
+
+	var validacao errosPorCampo
+	if errors.As(erro, &validacao) {
+		campos = validacao.Campos()
+	}
+
 	JSON(w, statusCode, struct {
-		Erro string `json:"erro"`
+		Erro   string              `json:"erro"`
+		Campos map[string][]string `json:"campos,omitempty"`
 	}{
-		Erro: erro.Error(),
+		Erro:   erro.Error(),
+		Campos: campos,
 	})
 }
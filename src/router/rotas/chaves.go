@@ -0,0 +1,13 @@
+package rotas
+
+import (
+	"api/src/controller"
+	"net/http"
+)
+
+var rotaChaves = Rota{
+	Uri:                "/.well-known/jwks.json",
+	Metodo:             http.MethodGet,
+	Funcao:             controller.BuscarChavesPublicas,
+	RequerAutenticacao: false,
+}
@@ -0,0 +1,87 @@
+package rotas
+
+import (
+	"api/src/controller"
+	"net/http"
+)
+
+var rotasConversas = []Rota{
+	{
+		Uri:                "/conversas",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BuscarConversas,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/conversas",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.CriarConversa,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/conversas/{conversaId}",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BuscarConversa,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/conversas/{conversaId}/mensagens",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BuscarMensagens,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/conversas/{conversaId}/mensagens",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.EnviarMensagem,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/conversas/{conversaId}/mensagens/{mensagemId}",
+		Metodo:             http.MethodDelete,
+		Funcao:             controller.DeletarMensagem,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/conversas/{conversaId}/ler",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.LerConversa,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/conversas/grupos",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.CriarGrupo,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/conversas/{conversaId}/membros",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.AdicionarMembro,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/conversas/{conversaId}/membros/{usuarioId}",
+		Metodo:             http.MethodDelete,
+		Funcao:             controller.RemoverMembro,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/conversas/{conversaId}/membros/{usuarioId}/admin",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.TornarAdmin,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/conversas/{conversaId}/membros/{usuarioId}/admin",
+		Metodo:             http.MethodDelete,
+		Funcao:             controller.RemoverAdmin,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/conversas/{conversaId}/sair",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.SairDoGrupo,
+		RequerAutenticacao: true,
+	},
+}
@@ -0,0 +1,20 @@
+package rotas
+
+import (
+	"api/src/controller"
+	"net/http"
+)
+
+var rotaEventos = Rota{
+	Uri:                "/eventos",
+	Metodo:             http.MethodGet,
+	Funcao:             controller.AcompanharEventos,
+	RequerAutenticacao: true,
+}
+
+var rotaWebSocket = Rota{
+	Uri:                "/ws",
+	Metodo:             http.MethodGet,
+	Funcao:             controller.ConectarWebSocket,
+	RequerAutenticacao: true,
+}
@@ -11,3 +11,25 @@ var rotaLogin = Rota{
 	Funcao:             controller.Login,
 	RequerAutenticacao: false,
 }
+
+var rotasLinkMagico = []Rota{
+	{
+		Uri:                "/login/link",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.SolicitarLinkMagico,
+		RequerAutenticacao: false,
+	},
+	{
+		Uri:                "/login/link/consumir",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.ConsumirLinkMagico,
+		RequerAutenticacao: false,
+	},
+}
+
+var rotaLogout = Rota{
+	Uri:                "/logout",
+	Metodo:             http.MethodPost,
+	Funcao:             controller.Logout,
+	RequerAutenticacao: true,
+}
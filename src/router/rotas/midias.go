@@ -0,0 +1,39 @@
+package rotas
+
+import (
+	"api/src/controller"
+	"net/http"
+)
+
+var rotasMidias = []Rota{
+	{
+		Uri:                "/midias",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.EnviarMidia,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/midias/{midiaId}",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BuscarMidia,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/midias/{midiaId}",
+		Metodo:             http.MethodDelete,
+		Funcao:             controller.DeletarMidia,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/midias/{midiaId}/{variante}",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BaixarMidia,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/usuarios/{usuarioId}/avatar",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.AtualizarAvatar,
+		RequerAutenticacao: true,
+	},
+}
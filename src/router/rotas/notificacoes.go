@@ -0,0 +1,45 @@
+package rotas
+
+import (
+	"api/src/controller"
+	"net/http"
+)
+
+var rotasNotificacoes = []Rota{
+	{
+		Uri:                "/notificacoes",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BuscarNotificacoes,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/notificacoes/nao-lidas",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.ContarNotificacoesNaoLidas,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/notificacoes/ler",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.LerTodasNotificacoes,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/notificacoes/preferencias",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BuscarPreferenciasDeNotificacao,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/notificacoes/preferencias",
+		Metodo:             http.MethodPut,
+		Funcao:             controller.AtualizarPreferenciasDeNotificacao,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/notificacoes/{notificacaoId}/ler",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.LerNotificacao,
+		RequerAutenticacao: true,
+	},
+}
@@ -30,6 +30,12 @@ var rotasPublicacoes = []Rota{
 		Funcao:             controller.AtualizarPublicacao,
 		RequerAutenticacao: true,
 	},
+	{
+		Uri:                "/publicacoes/{publicacaoId}",
+		Metodo:             http.MethodPatch,
+		Funcao:             controller.EditarPublicacao,
+		RequerAutenticacao: true,
+	},
 	{
 		Uri:                "/publicacoes/{publicacaoId}",
 		Metodo:             http.MethodDelete,
@@ -54,4 +60,16 @@ var rotasPublicacoes = []Rota{
 		Funcao:             controller.DescurtirPublicacao,
 		RequerAutenticacao: true,
 	},
+	{
+		Uri:                "/publicacoes/{publicacaoId}/repostar",
+		Metodo:             http.MethodPut,
+		Funcao:             controller.RepostarPublicacao,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/publicacoes/{publicacaoId}/desfazer-repostagem",
+		Metodo:             http.MethodPut,
+		Funcao:             controller.DesfazerRepostagem,
+		RequerAutenticacao: true,
+	},
 }
@@ -18,8 +18,18 @@ type Rota struct {
 // Configurar coloca as rotas no Router
 func Configurar(r *mux.Router) *mux.Router {
 	rotas := rotasUsuarios
-	rotas = append(rotas, rotaLogin)
+	rotas = append(rotas, rotaLogin, rotaLogout)
+	rotas = append(rotas, rotasLinkMagico...)
+	rotas = append(rotas, rotaChaves)
 	rotas = append(rotas, rotasPublicacoes...)
+	rotas = append(rotas, rotasSessoes...)
+	rotas = append(rotas, rotasMidias...)
+	rotas = append(rotas, rotasSolicitacoes...)
+	rotas = append(rotas, rotasSilenciamentos...)
+	rotas = append(rotas, rotasTags...)
+	rotas = append(rotas, rotasNotificacoes...)
+	rotas = append(rotas, rotasConversas...)
+	rotas = append(rotas, rotaEventos, rotaWebSocket)
 
 	for _, rota := range rotas {
 		if rota.RequerAutenticacao {
@@ -0,0 +1,27 @@
+package rotas
+
+import (
+	"api/src/controller"
+	"net/http"
+)
+
+var rotasSessoes = []Rota{
+	{
+		Uri:                "/sessoes",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BuscarSessoes,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/sessoes",
+		Metodo:             http.MethodDelete,
+		Funcao:             controller.RevogarOutrasSessoes,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/sessoes/{sessaoId}",
+		Metodo:             http.MethodDelete,
+		Funcao:             controller.RevogarSessao,
+		RequerAutenticacao: true,
+	},
+}
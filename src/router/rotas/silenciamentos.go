@@ -0,0 +1,45 @@
+package rotas
+
+import (
+	"api/src/controller"
+	"net/http"
+)
+
+var rotasSilenciamentos = []Rota{
+	{
+		Uri:                "/usuarios/{usuarioId}/silenciar",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.SilenciarUsuario,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/usuarios/{usuarioId}/dessilenciar",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.DessilenciarUsuario,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/silenciados/usuarios",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BuscarUsuariosSilenciados,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/silenciados/termos",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BuscarTermosSilenciados,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/silenciados/termos",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.SilenciarTermo,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/silenciados/termos/{termoId}",
+		Metodo:             http.MethodDelete,
+		Funcao:             controller.DessilenciarTermo,
+		RequerAutenticacao: true,
+	},
+}
@@ -0,0 +1,33 @@
+package rotas
+
+import (
+	"api/src/controller"
+	"net/http"
+)
+
+var rotasSolicitacoes = []Rota{
+	{
+		Uri:                "/solicitacoes/recebidas",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BuscarSolicitacoesRecebidas,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/solicitacoes/enviadas",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BuscarSolicitacoesEnviadas,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/solicitacoes/{seguidorId}/aprovar",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.AprovarSolicitacao,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/solicitacoes/{seguidorId}",
+		Metodo:             http.MethodDelete,
+		Funcao:             controller.RecusarSolicitacao,
+		RequerAutenticacao: true,
+	},
+}
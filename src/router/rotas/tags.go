@@ -0,0 +1,39 @@
+package rotas
+
+import (
+	"api/src/controller"
+	"net/http"
+)
+
+var rotasTags = []Rota{
+	{
+		Uri:                "/tags",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BuscarTags,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/tags/seguidas",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BuscarTagsSeguidas,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/tags/{tag}/publicacoes",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BuscarPublicacoesPorTag,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/tags/{tag}/seguir",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.SeguirTag,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/tags/{tag}/parar-de-seguir",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.PararDeSeguirTag,
+		RequerAutenticacao: true,
+	},
+}
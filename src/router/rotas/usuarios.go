@@ -30,6 +30,12 @@ var rotasUsuarios = []Rota{
 		Funcao:             controller.AtualizarUsuario,
 		RequerAutenticacao: true,
 	},
+	{
+		Uri:                "/usuarios/{usuarioId}",
+		Metodo:             http.MethodPatch,
+		Funcao:             controller.EditarUsuario,
+		RequerAutenticacao: true,
+	},
 	{
 		Uri:                "/usuarios/{usuarioId}",
 		Metodo:             http.MethodDelete,
@@ -66,4 +72,22 @@ var rotasUsuarios = []Rota{
 		Funcao:             controller.AtualizarSenha,
 		RequerAutenticacao: true,
 	},
+	{
+		Uri:                "/usuarios/{usuarioId}/bloquear",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.BloquearUsuario,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/usuarios/{usuarioId}/desbloquear",
+		Metodo:             http.MethodPost,
+		Funcao:             controller.DesbloquearUsuario,
+		RequerAutenticacao: true,
+	},
+	{
+		Uri:                "/bloqueios",
+		Metodo:             http.MethodGet,
+		Funcao:             controller.BuscarBloqueios,
+		RequerAutenticacao: true,
+	},
 }
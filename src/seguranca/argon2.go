@@ -0,0 +1,121 @@
+package seguranca
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	tamanhoSaltArgon2  = 16
+	tamanhoChaveArgon2 = 32
+)
+
+// Argon2id gera hashes Argon2id no formato $argon2id$v=19$m=...,t=...,p=...$salt$hash
+type Argon2id struct {
+	Memoria     uint32
+	Iteracoes   uint32
+	Paralelismo uint8
+}
+
+func (a Argon2id) Hash(senha []byte) (string, error) {
+	if erro := a.validar(); erro != nil {
+		return "", erro
+	}
+
+	salt := make([]byte, tamanhoSaltArgon2)
+	if _, erro := rand.Read(salt); erro != nil {
+		return "", erro
+	}
+
+	chave := argon2.IDKey(senha, salt, a.Iteracoes, a.Memoria, a.Paralelismo, tamanhoChaveArgon2)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, a.Memoria, a.Iteracoes, a.Paralelismo,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(chave),
+	), nil
+}
+
+func (a Argon2id) Verificar(hash string, senha []byte) error {
+	parametros, salt, chave, erro := decodificarArgon2id(hash)
+	if erro != nil {
+		return erro
+	}
+
+	calculada := argon2.IDKey(senha, salt, parametros.Iteracoes, parametros.Memoria, parametros.Paralelismo, uint32(len(chave)))
+	if subtle.ConstantTimeCompare(chave, calculada) != 1 {
+		return ErrSenhaIncorreta
+	}
+	return nil
+}
+
+func (a Argon2id) Reconhece(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func (a Argon2id) Atualizado(hash string) bool {
+	parametros, _, _, erro := decodificarArgon2id(hash)
+	if erro != nil {
+		return false
+	}
+	return parametros.Memoria >= a.Memoria && parametros.Iteracoes >= a.Iteracoes && parametros.Paralelismo >= a.Paralelismo
+}
+
+// validar confere os parâmetros antes de chamá-los no argon2, que entra em pânico com
+// paralelismo ou iterações zerados
+func (a Argon2id) validar() error {
+	if a.Paralelismo < 1 {
+		return errors.New("O paralelismo do argon2id deve ser ao menos 1")
+	}
+	if a.Iteracoes < 1 {
+		return errors.New("As iterações do argon2id devem ser ao menos 1")
+	}
+	if a.Memoria < 8*uint32(a.Paralelismo) {
+		return errors.New("A memória do argon2id deve ser ao menos 8 KiB por thread")
+	}
+	return nil
+}
+
+func decodificarArgon2id(hash string) (Argon2id, []byte, []byte, error) {
+	partes := strings.Split(hash, "$")
+	if len(partes) != 6 || partes[1] != "argon2id" {
+		return Argon2id{}, nil, nil, errors.New("Hash argon2id inválido")
+	}
+
+	var versao int
+	if _, erro := fmt.Sscanf(partes[2], "v=%d", &versao); erro != nil || versao != argon2.Version {
+		return Argon2id{}, nil, nil, errors.New("Versão do argon2id não suportada")
+	}
+
+	var parametros Argon2id
+	if _, erro := fmt.Sscanf(partes[3], "m=%d,t=%d,p=%d",
+		&parametros.Memoria, &parametros.Iteracoes, &parametros.Paralelismo); erro != nil {
+		return Argon2id{}, nil, nil, errors.New("Parâmetros do argon2id inválidos")
+	}
+	if erro := parametros.validar(); erro != nil {
+		return Argon2id{}, nil, nil, erro
+	}
+
+	salt, erro := base64.RawStdEncoding.DecodeString(partes[4])
+	if erro != nil {
+		return Argon2id{}, nil, nil, erro
+	}
+
+	chave, erro := base64.RawStdEncoding.DecodeString(partes[5])
+	if erro != nil {
+		return Argon2id{}, nil, nil, erro
+	}
+
+	// Uma chave vazia seria igual a qualquer senha na comparação
+	if len(salt) == 0 || len(chave) == 0 {
+		return Argon2id{}, nil, nil, errors.New("Hash argon2id inválido")
+	}
+
+	return parametros, salt, chave, nil
+}
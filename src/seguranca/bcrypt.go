@@ -0,0 +1,38 @@
+package seguranca
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt gera hashes bcrypt com o custo informado
+type Bcrypt struct {
+	Custo int
+}
+
+func (b Bcrypt) Hash(senha []byte) (string, error) {
+	hash, erro := bcrypt.GenerateFromPassword(senha, b.Custo)
+	if erro != nil {
+		return "", erro
+	}
+	return string(hash), nil
+}
+
+func (b Bcrypt) Verificar(hash string, senha []byte) error {
+	erro := bcrypt.CompareHashAndPassword([]byte(hash), senha)
+	if errors.Is(erro, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrSenhaIncorreta
+	}
+	return erro
+}
+
+func (b Bcrypt) Reconhece(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (b Bcrypt) Atualizado(hash string) bool {
+	custo, erro := bcrypt.Cost([]byte(hash))
+	return erro == nil && custo >= b.Custo
+}
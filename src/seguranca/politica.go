@@ -0,0 +1,126 @@
+package seguranca
+
+import (
+	"api/src/config"
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tamanhoMaximoSenha respeita o limite de 72 bytes do bcrypt
+const tamanhoMaximoSenha = 72
+
+// senhasVazadas guarda os hashes SHA-1 agrupados pelo prefixo de 5 caracteres,
+// o mesmo formato de k-anonimato da API de range do Have I Been Pwned
+var senhasVazadas = map[string]map[string]struct{}{}
+
+// CarregarSenhasVazadas lê o arquivo de hashes de senhas vazadas configurado
+func CarregarSenhasVazadas() error {
+	senhasVazadas = map[string]map[string]struct{}{}
+
+	if config.SenhasVazadasArquivo == "" {
+		return nil
+	}
+
+	arquivo, erro := os.Open(config.SenhasVazadasArquivo)
+	if erro != nil {
+		return erro
+	}
+	defer arquivo.Close()
+
+	scanner := bufio.NewScanner(arquivo)
+	for scanner.Scan() {
+		linha := strings.TrimSpace(scanner.Text())
+		if linha == "" || strings.HasPrefix(linha, "#") {
+			continue
+		}
+
+		hash := strings.ToUpper(strings.SplitN(linha, ":", 2)[0])
+		if len(hash) != sha1.Size*2 {
+			return fmt.Errorf("%s: hash inválido %q", config.SenhasVazadasArquivo, hash)
+		}
+
+		prefixo, sufixo := hash[:5], hash[5:]
+		if senhasVazadas[prefixo] == nil {
+			senhasVazadas[prefixo] = map[string]struct{}{}
+		}
+		senhasVazadas[prefixo][sufixo] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+// SenhaVazada verifica se a senha aparece na lista de senhas vazadas
+func SenhaVazada(senha string) bool {
+	soma := sha1.Sum([]byte(senha))
+	hash := strings.ToUpper(hex.EncodeToString(soma[:]))
+
+	_, vazada := senhasVazadas[hash[:5]][hash[5:]]
+	return vazada
+}
+
+// ValidarSenha aplica a política de senhas e retorna as regras que a senha não cumpre.
+// Nick e email são usados para rejeitar senhas que contenham os dados do próprio usuário
+func ValidarSenha(senha, nick, email string) []string {
+	var problemas []string
+
+	if utf8.RuneCountInString(senha) < config.SenhaTamanhoMinimo {
+		problemas = append(problemas, fmt.Sprintf("A senha deve ter pelo menos %d caracteres", config.SenhaTamanhoMinimo))
+	}
+
+	if len(senha) > tamanhoMaximoSenha {
+		problemas = append(problemas, fmt.Sprintf("A senha deve ter no máximo %d bytes", tamanhoMaximoSenha))
+	}
+
+	if classes := contarClasses(senha); classes < config.SenhaClassesMinimas {
+		problemas = append(problemas, fmt.Sprintf(
+			"A senha deve combinar pelo menos %d entre letras minúsculas, maiúsculas, números e símbolos",
+			config.SenhaClassesMinimas,
+		))
+	}
+
+	senhaMinuscula := strings.ToLower(senha)
+	usuarioDeEmail := strings.SplitN(email, "@", 2)[0]
+	for _, dado := range []string{nick, email, usuarioDeEmail} {
+		dado = strings.ToLower(strings.TrimSpace(dado))
+		if utf8.RuneCountInString(dado) >= 3 && strings.Contains(senhaMinuscula, dado) {
+			problemas = append(problemas, "A senha não pode conter o seu nick ou email")
+			break
+		}
+	}
+
+	if SenhaVazada(senha) {
+		problemas = append(problemas, "Essa senha aparece em vazamentos conhecidos, escolha outra")
+	}
+
+	return problemas
+}
+
+func contarClasses(senha string) int {
+	var minuscula, maiuscula, numero, simbolo bool
+
+	for _, caractere := range senha {
+		switch {
+		case unicode.IsLower(caractere):
+			minuscula = true
+		case unicode.IsUpper(caractere):
+			maiuscula = true
+		case unicode.IsDigit(caractere):
+			numero = true
+		default:
+			simbolo = true
+		}
+	}
+
+	classes := 0
+	for _, presente := range []bool{minuscula, maiuscula, numero, simbolo} {
+		if presente {
+			classes++
+		}
+	}
+	return classes
+}
@@ -1,13 +1,99 @@
 package seguranca
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"api/src/config"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// Hasher é implementado pelos algoritmos de hash de senha suportados
+type Hasher interface {
+	// Hash gera o hash da senha já com o pepper aplicado
+	Hash(senha []byte) (string, error)
+	// Verificar compara o hash salvo com a senha
+	Verificar(hash string, senha []byte) error
+	// Reconhece informa se o hash foi gerado por esse algoritmo
+	Reconhece(hash string) bool
+	// Atualizado informa se o hash usa os parâmetros configurados atualmente
+	Atualizado(hash string) bool
+}
+
+// ErrSenhaIncorreta é retornado quando a senha não corresponde ao hash salvo
+var ErrSenhaIncorreta = errors.New("Senha incorreta")
+
+// hasherConfigurado retorna o algoritmo usado para os hashes novos
+func hasherConfigurado() Hasher {
+	if config.HashAlgoritmo == "bcrypt" {
+		return Bcrypt{Custo: config.BcryptCusto}
+	}
+	return Argon2id{
+		Memoria:     uint32(config.Argon2Memoria),
+		Iteracoes:   uint32(config.Argon2Iteracoes),
+		Paralelismo: uint8(config.Argon2Paralelismo),
+	}
+}
+
+// reconhecer descobre qual algoritmo gerou o hash salvo
+func reconhecer(hash string) (Hasher, error) {
+	for _, hasher := range []Hasher{hasherConfigurado(), Argon2id{}, Bcrypt{}} {
+		if hasher.Reconhece(hash) {
+			return hasher, nil
+		}
+	}
+	return nil, errors.New("Formato de hash de senha desconhecido")
+}
+
+// comPepper mistura o segredo do servidor na senha antes do hash. Sem pepper configurado a senha passa intacta
+func comPepper(senha string) []byte {
+	if config.HashPepper == "" {
+		return []byte(senha)
+	}
+
+	mac := hmac.New(sha256.New, []byte(config.HashPepper))
+	mac.Write([]byte(senha))
+	return []byte(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
 
 //Hash recebe uma string e coloca um hash nela
 func Hash(senha string) ([]byte, error) {
-	return bcrypt.GenerateFromPassword([]byte(senha), bcrypt.DefaultCost)
+	hash, erro := hasherConfigurado().Hash(comPepper(senha))
+	if erro != nil {
+		return nil, erro
+	}
+	return []byte(hash), nil
+}
+
+// Verificar compara o hash salvo com a senha e informa se o hash deve ser refeito,
+// seja por usar um algoritmo ou parâmetros antigos, seja por ter sido gerado sem o pepper
+func Verificar(hash string, senha string) (bool, error) {
+	if hash == "" {
+		return false, ErrSenhaIncorreta
+	}
+
+	hasher, erro := reconhecer(hash)
+	if erro != nil {
+		return false, erro
+	}
+
+	configurado := hasherConfigurado()
+	precisaRehash := !configurado.Reconhece(hash) || !configurado.Atualizado(hash)
+
+	erro = hasher.Verificar(hash, comPepper(senha))
+	if erro == nil {
+		return precisaRehash, nil
+	}
+
+	// Hashes gerados antes do pepper ser configurado continuam válidos até o próximo login
+	if config.HashPepper != "" && hasher.Verificar(hash, []byte(senha)) == nil {
+		return true, nil
+	}
+	return false, ErrSenhaIncorreta
 }
 
 //Verificar senha compara hash com string
 func VerificarSenha(senhaString string, hash string) error {
-	return bcrypt.CompareHashAndPassword([]byte(senhaString), []byte(hash))
+	_, erro := Verificar(senhaString, hash)
+	return erro
 }